@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/segmentio/analytics-go"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	failureCategoryExitCode       = "exit_code"
+	failureCategoryExpectation    = "expectation_failed"
+	failureCategorySnapshot       = "snapshot_mismatch"
+	failureCategoryInfrastructure = "infrastructure"
+
+	// buildAttemptEnvKey is the attempt number of the Bitrise build, greater than 1 if the build is retried.
+	buildAttemptEnvKey = "BITRISE_BUILD_ATTEMPT"
+)
+
+// analyticsContext describes the checked step and the environment of the check, it is attached to the analytics events.
+type analyticsContext struct {
+	StepID string
+	// StepVersion is the tag of the checked commit, or the commit itself if it is not a release.
+	StepVersion    string
+	GitCommit      string
+	GitBranch      string
+	BitriseVersion string
+	OS             string
+	Arch           string
+	// Attempt is the attempt number of the build running the check, 1 unless the build is retried.
+	Attempt int
+}
+
+type stepSourceModel struct {
+	SourceCodeURL string `yaml:"source_code_url"`
+	Website       string `yaml:"website"`
+}
+
+// newAnalyticsContext collects the context on a best effort basis, unknown values are left empty.
+func newAnalyticsContext(commandFactory command.Factory, workDir string) analyticsContext {
+	run := func(name string, args ...string) string {
+		out, err := commandFactory.Create(name, args, &command.Opts{Dir: workDir}).RunAndReturnTrimmedOutput()
+		if err != nil {
+			return ""
+		}
+		return out
+	}
+
+	context := analyticsContext{
+		StepID:         readStepID(filepath.Join(workDir, "step.yml")),
+		GitCommit:      os.Getenv("BITRISE_GIT_COMMIT"),
+		GitBranch:      os.Getenv("BITRISE_GIT_BRANCH"),
+		BitriseVersion: run("bitrise", "--version"),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		Attempt:        readBuildAttempt(),
+	}
+	// CI builds usually check out a detached HEAD, the git env vars of the build are preferred
+	if context.GitCommit == "" {
+		context.GitCommit = run("git", "rev-parse", "HEAD")
+	}
+	if context.GitBranch == "" {
+		context.GitBranch = run("git", "rev-parse", "--abbrev-ref", "HEAD")
+	}
+	// The checked step is the release only if its tag points to the checked out commit
+	context.StepVersion = run("git", "describe", "--tags", "--exact-match", context.GitCommit)
+	if context.StepVersion == "" {
+		context.StepVersion = context.GitCommit
+	}
+
+	return context
+}
+
+// readBuildAttempt returns the attempt number of the build, it defaults to 1 if the build does not expose it.
+func readBuildAttempt() int {
+	attempt, err := strconv.Atoi(os.Getenv(buildAttemptEnvKey))
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// readStepID returns the step ID, derived from the repository name in step.yml (for example steps-xcode-archive),
+// as step.yml does not hold the ID of the step.
+func readStepID(stepYMLPath string) string {
+	stepBytes, err := ioutil.ReadFile(stepYMLPath)
+	if err != nil {
+		return ""
+	}
+
+	var model stepSourceModel
+	if err := yaml.Unmarshal(stepBytes, &model); err != nil {
+		return ""
+	}
+
+	repoURL := model.SourceCodeURL
+	if repoURL == "" {
+		repoURL = model.Website
+	}
+	repoURL = strings.TrimSuffix(strings.TrimSuffix(repoURL, "/"), ".git")
+	if repoURL == "" {
+		return ""
+	}
+
+	id := repoURL[strings.LastIndexAny(repoURL, "/:")+1:]
+	for _, prefix := range []string{"steps-", "bitrise-step-"} {
+		id = strings.TrimPrefix(id, prefix)
+	}
+	return id
+}
+
+func (c analyticsContext) properties() map[string]interface{} {
+	return map[string]interface{}{
+		"step_id":         c.StepID,
+		"step_version":    c.StepVersion,
+		"git_commit":      c.GitCommit,
+		"git_branch":      c.GitBranch,
+		"bitrise_version": c.BitriseVersion,
+		"os":              c.OS,
+		"arch":            c.Arch,
+		"attempt":         c.Attempt,
+	}
+}
+
+// failureCategory groups the errors of the checks by their cause, it is empty for successful checks.
+func failureCategory(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, errSnapshotMismatch):
+		return failureCategorySnapshot
+	case errors.Is(err, errExpectationFailed):
+		return failureCategoryExpectation
+	}
+	if _, ok := exitCodeOf(err); ok {
+		return failureCategoryExitCode
+	}
+	return failureCategoryInfrastructure
+}
+
+// sendCheckAnalytics sends the ci_check_finished event of a check workflow (like lint, unit_test or e2e).
+func sendCheckAnalytics(client analytics.Client, context analyticsContext, parentURL, workflow string, err error, duration time.Duration) error {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	properties := context.properties()
+	properties["workflow"] = workflow
+	properties["status"] = status
+	properties["failure_category"] = failureCategory(err)
+	properties["parent_url"] = parentURL
+	properties["stack_id"] = os.Getenv("BITRISEIO_STACK_ID")
+	properties["duration"] = duration.Milliseconds()
+
+	return client.Enqueue(analytics.Track{
+		UserId:     unifiedCiAppID,
+		Event:      "ci_check_finished",
+		Properties: properties,
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/analytics-go"
+)
+
+type recordingAnalyticsClient struct {
+	messages []analytics.Message
+}
+
+func (c *recordingAnalyticsClient) Enqueue(message analytics.Message) error {
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *recordingAnalyticsClient) Close() error {
+	return nil
+}
+
+func Test_readStepID(t *testing.T) {
+	tests := []struct {
+		name    string
+		stepYML string
+		want    string
+	}{
+		{"Source code URL", "source_code_url: https://github.com/bitrise-steplib/steps-xcode-archive\nwebsite: https://github.com/bitrise-io/other", "xcode-archive"},
+		{"Website with .git suffix", "website: https://github.com/bitrise-steplib/steps-check.git", "check"},
+		{"SSH URL", "source_code_url: git@github.com:bitrise-steplib/bitrise-step-save-cache.git", "save-cache"},
+		{"No URL", "title: Step", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "step.yml")
+			if err := ioutil.WriteFile(pth, []byte(tt.stepYML), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if got := readStepID(pth); got != tt.want {
+				t.Errorf("readStepID() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_failureCategory(t *testing.T) {
+	exitErr := exec.Command("false").Run()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Success", nil, ""},
+		{"Snapshot mismatch", fmt.Errorf("%w: outputs.json", errSnapshotMismatch), failureCategorySnapshot},
+		{"Expectation failed", withRepro(fmt.Errorf("%w: exit code", errExpectationFailed), reproScript{}), failureCategoryExpectation},
+		{"Exit code", withRepro(exitErr, reproScript{}), failureCategoryExitCode},
+		{"Infrastructure", fmt.Errorf("failed to run command: not found"), failureCategoryInfrastructure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureCategory(tt.err); got != tt.want {
+				t.Errorf("failureCategory() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readBuildAttempt(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt string
+		want    int
+	}{
+		{"Not set", "", 1},
+		{"Retried build", "2", 2},
+		{"Invalid", "second", 1},
+		{"Zero", "0", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(buildAttemptEnvKey, tt.attempt)
+			if got := readBuildAttempt(); got != tt.want {
+				t.Errorf("readBuildAttempt() got = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sendCheckAnalytics(t *testing.T) {
+	client := &recordingAnalyticsClient{}
+	context := analyticsContext{StepID: "check", OS: "linux", Attempt: 2}
+	if err := sendCheckAnalytics(client, context, "https://app.bitrise.io/build/1", "lint", withRepro(exec.Command("false").Run(), reproScript{}), 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.messages) != 1 {
+		t.Fatalf("sendCheckAnalytics() sent %d messages, want 1", len(client.messages))
+	}
+	track := client.messages[0].(analytics.Track)
+	if track.Event != "ci_check_finished" {
+		t.Errorf("sendCheckAnalytics() event = %s", track.Event)
+	}
+	for key, want := range map[string]interface{}{
+		"workflow":         "lint",
+		"status":           "error",
+		"failure_category": failureCategoryExitCode,
+		"duration":         int64(1500),
+		"step_id":          "check",
+		"os":               "linux",
+		"attempt":          2,
+	} {
+		if got := track.Properties[key]; got != want {
+			t.Errorf("sendCheckAnalytics() %s = %v, want %v", key, got, want)
+		}
+	}
+}
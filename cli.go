@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"github.com/bitrise-io/go-utils/errorutil"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const cliUsage = `Usage: steps-check [command]
+
+Without a command, runs as a Bitrise step, configured by the step inputs.
+
+Commands:
+  help                                     Print this help
+  audit [step.yml]                         Audit the step.yml, like the lint check does
+  schema [step.yml]                        Validate the step.yml against the step.yml JSON schema
+  readme [step dir]                        Check if README.md is up to date with step.yml
+  yamllint [dir]                           Lint the YAML files with the repo's or the embedded yamllint config
+  yamlfmt [-w] [dir]                       Check the formatting of the .yml files with the repo's or the embedded yamlfmt config,
+                                           -w formats the files in place
+  baseline [dir]                           Record the current findings of the native lint checks and golangci-lint
+                                           into the findings baseline, only new findings fail these checks afterwards
+  secrets keygen                           Generate a new secrets key
+  secrets encrypt [plaintext] [encrypted]  Encrypt the E2E secrets inventory with $%[1]s
+  secrets decrypt [encrypted] [plaintext]  Decrypt the E2E secrets inventory with $%[1]s
+
+The native checks apply the .steps-check.yml of the repo (the step.yml's or the given directory).
+The secrets inventory paths default to e2e/%[2]s and e2e/%[3]s.
+`
+
+// golangciLintVersion is the golangci-lint version installed by the lint workflow (checks.bitrise.yml).
+// The baseline should be recorded with the same version, as the linters of other versions report other findings.
+const golangciLintVersion = "1.64.8"
+
+var golangciLintVersionRegexp = regexp.MustCompile(`version v?(\d+\.\d+\.\d+)`)
+
+// runCommand runs the command line tooling of the repo, when the binary is invoked with arguments.
+func runCommand(args []string) error {
+	usage := fmt.Sprintf(cliUsage, secretsKeyEnvKey, defaultBitriseSecretsName, encryptedSecretsFileName)
+	usageErr := errors.New(usage)
+
+	switch args[0] {
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+		return nil
+	case "audit":
+		stepYMLPath := "step.yml"
+		if len(args) > 1 {
+			stepYMLPath = args[1]
+		}
+		return runRepoNativeCheck(filepath.Dir(stepYMLPath), nativeCheck{checkAudit, auditCheckTitle, func() ([]diagnostic, error) {
+			return auditStepYML(stepYMLPath)
+		}})
+	case "schema":
+		stepYMLPath := "step.yml"
+		if len(args) > 1 {
+			stepYMLPath = args[1]
+		}
+		return runRepoNativeCheck(filepath.Dir(stepYMLPath), nativeCheck{checkSchema, schemaCheckTitle, func() ([]diagnostic, error) {
+			return validateStepSchema(stepYMLPath)
+		}})
+	case "readme":
+		workDir := "."
+		if len(args) > 1 {
+			workDir = args[1]
+		}
+		return runRepoNativeCheck(workDir, nativeCheck{checkREADMEDiff, readmeCheckTitle, func() ([]diagnostic, error) {
+			return checkREADME(workDir)
+		}})
+	case "yamllint":
+		dir := "."
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return runRepoNativeCheck(dir, nativeCheck{checkYAMLLint, yamllintCheckTitle, func() ([]diagnostic, error) {
+			return lintYAMLFiles(dir, yamllintConfig)
+		}})
+	case "yamlfmt":
+		write := len(args) > 1 && args[1] == "-w"
+		if write {
+			args = args[1:]
+		}
+		dir := "."
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return runRepoNativeCheck(dir, nativeCheck{checkYAMLFmt, yamlfmtCheckTitle, func() ([]diagnostic, error) {
+			return formatYAMLFiles(dir, yamlfmtConfig, write)
+		}})
+	case "baseline":
+		dir := "."
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return recordFindingsBaseline(dir)
+	case "secrets":
+		if len(args) < 2 {
+			return usageErr
+		}
+		return runSecretsCommand(args[1], args[2:], usageErr)
+	default:
+		return usageErr
+	}
+}
+
+// runRepoNativeCheck runs the check with the .steps-check.yml and the findings baseline of the repo directory applied,
+// like the step does.
+func runRepoNativeCheck(dir string, check nativeCheck) error {
+	repoCfg, err := readRepoConfig(dir)
+	if err != nil {
+		return err
+	}
+	baseline, err := readFindingsBaseline(dir, repoCfg)
+	if err != nil {
+		return err
+	}
+	_, err = runNativeCheck(withFindingsBaseline(baseline, withRepoConfig(dir, repoCfg, check)))
+	return err
+}
+
+// recordFindingsBaseline runs the native lint checks and golangci-lint, which are enabled by default or in the repo
+// config, and records all their findings into the repo's findings baseline.
+func recordFindingsBaseline(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	repoCfg, err := readRepoConfig(dir)
+	if err != nil {
+		return err
+	}
+
+	enabled := enabledChecks(Config{}, repoCfg)
+	var diagnostics []diagnostic
+	for _, check := range nativeLintChecks(dir, enabled, repoCfg) {
+		log.Infof("Running %s", check.title)
+		checkDiagnostics, err := check.run()
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", check.title, err)
+		}
+		diagnostics = append(diagnostics, checkDiagnostics...)
+	}
+
+	if enabled[checkGolangciLint] {
+		if _, err := exec.LookPath("golangci-lint"); err != nil {
+			log.Warnf("golangci-lint is not installed, its findings are not recorded")
+		} else {
+			if version := installedGolangciLintVersion(dir); version != golangciLintVersion {
+				if version == "" {
+					version = "of unknown version"
+				}
+				log.Warnf("golangci-lint %s is installed, the lint workflow runs %s, the recorded findings may differ from its findings", version, golangciLintVersion)
+			}
+
+			log.Infof("Running golangci-lint")
+			lintDiagnostics, err := runGolangciLint(dir)
+			if err != nil {
+				return fmt.Errorf("golangci-lint failed: %w", err)
+			}
+			diagnostics = append(diagnostics, repoCfg.apply(lintDiagnostics)...)
+		}
+	}
+
+	pth, err := writeFindingsBaseline(dir, repoCfg, diagnostics)
+	if err != nil {
+		return err
+	}
+	log.Donef("Recorded %d finding(s) into %s", len(diagnostics), pth)
+	return nil
+}
+
+// runGolangciLint runs golangci-lint with the repo's config, like the lint workflow does, reporting all the issues.
+func runGolangciLint(dir string) ([]diagnostic, error) {
+	cmd := exec.Command("golangci-lint", "run", "--timeout", "5m", "--max-issues-per-linter", "0", "--max-same-issues", "0")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	diagnostics := parseGolangciLintOutput(string(output))
+	// golangci-lint exits with 1 if it found issues
+	if err != nil && (!errorutil.IsExitStatusError(err) || len(diagnostics) == 0) {
+		return nil, err
+	}
+	return diagnostics, nil
+}
+
+// installedGolangciLintVersion returns the version of the golangci-lint on PATH, or an empty string if it is unknown.
+func installedGolangciLintVersion(dir string) string {
+	cmd := exec.Command("golangci-lint", "--version")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return parseGolangciLintVersion(string(output))
+}
+
+func parseGolangciLintVersion(output string) string {
+	match := golangciLintVersionRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func runSecretsCommand(command string, args []string, usageErr error) error {
+	plaintextPath := filepath.Join("e2e", defaultBitriseSecretsName)
+	encryptedPath := filepath.Join("e2e", encryptedSecretsFileName)
+	key := os.Getenv(secretsKeyEnvKey)
+
+	switch command {
+	case "keygen":
+		key, err := generateSecretsKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		return nil
+	case "encrypt":
+		if len(args) > 0 {
+			plaintextPath = args[0]
+		}
+		if len(args) > 1 {
+			encryptedPath = args[1]
+		}
+
+		plaintext, err := ioutil.ReadFile(plaintextPath)
+		if err != nil {
+			return err
+		}
+		encrypted, err := encryptSecrets(plaintext, key)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(encryptedPath, encrypted, 0644); err != nil {
+			return err
+		}
+
+		log.Donef("Encrypted %s into %s", plaintextPath, encryptedPath)
+		log.Printf("Make sure %s is not committed", plaintextPath)
+		return nil
+	case "decrypt":
+		if len(args) > 0 {
+			encryptedPath = args[0]
+		}
+		if len(args) > 1 {
+			plaintextPath = args[1]
+		}
+
+		encrypted, err := ioutil.ReadFile(encryptedPath)
+		if err != nil {
+			return err
+		}
+		plaintext, err := decryptSecrets(encrypted, key)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(plaintextPath, plaintext, 0600); err != nil {
+			return err
+		}
+
+		log.Donef("Decrypted %s into %s", encryptedPath, plaintextPath)
+		log.Printf("Edit it, then run 'secrets encrypt' to update the encrypted inventory")
+		return nil
+	default:
+		return usageErr
+	}
+}
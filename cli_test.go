@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_runCommand_usage(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"Help", []string{"help"}, false},
+		{"Short help flag", []string{"-h"}, false},
+		{"Long help flag", []string{"--help"}, false},
+		{"Unknown command", []string{"unknown"}, true},
+		{"Secrets without command", []string{"secrets"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runCommand(tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_parseGolangciLintVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"Release", "golangci-lint has version 1.64.8 built with go1.24.1 from 8b37f141 on 2025-03-17T20:41:53Z", "1.64.8"},
+		{"Prefixed version", "golangci-lint has version v2.11.4 built with go1.26.0", "2.11.4"},
+		{"Unknown format", "golangci-lint dev", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGolangciLintVersion(tt.output); got != tt.want {
+				t.Errorf("parseGolangciLintVersion() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_golangciLintVersion_lintWorkflow(t *testing.T) {
+	if !strings.Contains(checkConfig, "sh -s -- v"+golangciLintVersion+"\n") {
+		t.Errorf("the lint workflow of checks.bitrise.yml should install golangci-lint %s", golangciLintVersion)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// IDs of the checks reporting diagnostics, the native ones match the CLI command running them.
+const (
+	checkAudit        = "audit"
+	checkSchema       = "schema"
+	checkREADMEDiff   = "readme"
+	checkYAMLLint     = "yamllint"
+	checkYAMLFmt      = "yamlfmt"
+	checkGolangciLint = "golangci-lint"
+	checkGoTest       = "go-test"
+	checkBaseline     = "baseline"
+)
+
+// Titles of the native checks, as printed in the log.
+const (
+	auditCheckTitle    = "step.yml audit"
+	schemaCheckTitle   = "step.yml schema validation"
+	readmeCheckTitle   = "README check"
+	yamllintCheckTitle = "YAML lint"
+	yamlfmtCheckTitle  = "YAML formatting check"
+)
+
+var (
+	ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	// golangci-lint's text output: path/to/file.go:12:5: message (linter)
+	golangciLintLineRegex = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+) \(([\w-]+)\)$`)
+	// go test's output: the test log is indented, with the file and line of the t.Error call
+	goTestRunRegex  = regexp.MustCompile(`^=== (?:RUN|CONT|PAUSE)\s+(\S+)`)
+	goTestFailRegex = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	goTestLogRegex  = regexp.MustCompile(`^\s+(\S+_test\.go):(\d+): (.+)$`)
+	// go test's package result: ok, FAIL or ? followed by the import path of the package
+	goTestPackageRegex = regexp.MustCompile(`^(?:ok|FAIL|\?)\s+(\S+)(?:\s|$)`)
+	goModModuleRegex   = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+)
+
+// diagnostic is a finding of a check, located in a file of the step repo.
+// Line and Column are 1-based, Line is 0 for findings about the whole file.
+type diagnostic struct {
+	Check    string
+	Rule     string
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+	// Fix is an optional suggestion on how to fix the finding.
+	Fix string
+	// Details is optional output printed below the finding, like the unified diff of the expected content.
+	Details string
+	// Baselined findings are accepted by the repo's findings baseline, they don't fail the check.
+	Baselined bool
+}
+
+func (d diagnostic) location() string {
+	switch {
+	case d.Line == 0:
+		return d.File
+	case d.Column == 0:
+		return fmt.Sprintf("%s:%d", d.File, d.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+}
+
+func (d diagnostic) String() string {
+	s := fmt.Sprintf("%s: %s: %s", d.location(), d.Severity, d.Message)
+	if d.Rule != "" {
+		s += fmt.Sprintf(" (%s)", d.Rule)
+	}
+	return s
+}
+
+// reportDiagnostics prints the diagnostics of a check, and returns an error if any of them is a not baselined error.
+func reportDiagnostics(check string, diagnostics []diagnostic) error {
+	errorCount, baselinedCount := 0, 0
+	for _, d := range diagnostics {
+		switch {
+		case d.Baselined:
+			baselinedCount++
+			log.Printf("%s (baselined)", d)
+		case d.Severity == severityError:
+			errorCount++
+			log.Printf("%s", colorstring.Red(d))
+		default:
+			log.Printf("%s", colorstring.Yellow(d))
+		}
+		if d.Details != "" {
+			log.Printf("%s", d.Details)
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%s found %d error(s)", check, errorCount)
+	}
+
+	if baselinedCount > 0 {
+		log.Donef("%s passed with %d warning(s), %d baselined finding(s)", check, len(diagnostics)-baselinedCount, baselinedCount)
+		return nil
+	}
+	log.Donef("%s passed with %d warning(s)", check, len(diagnostics))
+	return nil
+}
+
+// nativeCheck is a check implemented by the step itself.
+type nativeCheck struct {
+	id    string
+	title string
+	run   func() ([]diagnostic, error)
+}
+
+// runNativeCheck runs the check and prints its diagnostics.
+// It returns an error if the check could not run, or if it found any error level diagnostic.
+func runNativeCheck(check nativeCheck) ([]diagnostic, error) {
+	fmt.Println()
+	log.Infof("Running %s", check.title)
+	diagnostics, err := check.run()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", check.title, err)
+	}
+	return diagnostics, reportDiagnostics(check.title, diagnostics)
+}
+
+// withRepoConfig applies the repo config to the diagnostics of the check, which are made relative to the repo's directory.
+func withRepoConfig(dir string, repoCfg repoConfig, check nativeCheck) nativeCheck {
+	run := check.run
+	check.run = func() ([]diagnostic, error) {
+		diagnostics, err := run()
+		if err != nil {
+			return nil, err
+		}
+		return repoCfg.apply(relativeDiagnostics(dir, diagnostics)), nil
+	}
+	return check
+}
+
+// relativeDiagnostics makes the absolute file paths of the diagnostics relative to the directory.
+func relativeDiagnostics(dir string, diagnostics []diagnostic) []diagnostic {
+	for i, d := range diagnostics {
+		if !filepath.IsAbs(d.File) {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, d.File); err == nil {
+			diagnostics[i].File = filepath.ToSlash(rel)
+		}
+	}
+	return diagnostics
+}
+
+// sortDiagnostics sorts the diagnostics by file, location, check and message.
+func sortDiagnostics(diagnostics []diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Message < b.Message
+	})
+}
+
+// printDiagnosticsSummary prints the diagnostics of all the checks, grouped by file.
+// Baselined diagnostics are only counted.
+func printDiagnosticsSummary(diagnostics []diagnostic) {
+	var sorted []diagnostic
+	for _, d := range diagnostics {
+		if !d.Baselined {
+			sorted = append(sorted, d)
+		}
+	}
+	baselinedCount := len(diagnostics) - len(sorted)
+	if len(sorted) == 0 {
+		if baselinedCount > 0 {
+			fmt.Println()
+			log.Infof("Findings: %d baselined", baselinedCount)
+		}
+		return
+	}
+	sortDiagnostics(sorted)
+
+	errorCount := 0
+	for _, d := range sorted {
+		if d.Severity == severityError {
+			errorCount++
+		}
+	}
+
+	fmt.Println()
+	if baselinedCount > 0 {
+		log.Infof("Findings: %d error(s), %d warning(s), %d baselined", errorCount, len(sorted)-errorCount, baselinedCount)
+	} else {
+		log.Infof("Findings: %d error(s), %d warning(s)", errorCount, len(sorted)-errorCount)
+	}
+	for i, d := range sorted {
+		if i == 0 || d.File != sorted[i-1].File {
+			log.Printf("%s", d.File)
+		}
+
+		location := "-"
+		if d.Line > 0 {
+			location = strconv.Itoa(d.Line)
+			if d.Column > 0 {
+				location += ":" + strconv.Itoa(d.Column)
+			}
+		}
+		id := d.Check
+		if d.Rule != "" {
+			id += "/" + d.Rule
+		}
+		line := fmt.Sprintf("  %-7s %-7s %s [%s]", location, d.Severity, d.Message, id)
+		if d.Severity == severityError {
+			log.Printf("%s", colorstring.Red(line))
+		} else {
+			log.Printf("%s", colorstring.Yellow(line))
+		}
+		if d.Fix != "" {
+			log.Printf("          fix: %s", d.Fix)
+		}
+	}
+}
+
+// workflowCheckID returns the ID of the check, which reports the diagnostics of the workflow.
+func workflowCheckID(workflow string) string {
+	switch workflow {
+	case lintWorkflow:
+		return checkGolangciLint
+	case unitTestWorkflow:
+		return checkGoTest
+	default:
+		return workflow
+	}
+}
+
+// parseWorkflowDiagnostics converts the output of the external tools run by the check workflow in the work dir
+// into diagnostics.
+func parseWorkflowDiagnostics(workDir, workflow, output string) []diagnostic {
+	switch workflow {
+	case lintWorkflow:
+		return parseGolangciLintOutput(output)
+	case unitTestWorkflow:
+		return parseGoTestOutput(output, goModulePath(workDir))
+	default:
+		return nil
+	}
+}
+
+func parseGolangciLintOutput(output string) []diagnostic {
+	var diagnostics []diagnostic
+	for _, line := range strings.Split(ansiEscapeRegex.ReplaceAllString(output, ""), "\n") {
+		match := golangciLintLineRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostics = append(diagnostics, diagnostic{
+			Check:    checkGolangciLint,
+			Rule:     match[5],
+			File:     match[1],
+			Line:     lineNumber,
+			Column:   column,
+			Severity: severityError,
+			Message:  match[4],
+		})
+	}
+	return diagnostics
+}
+
+// goModulePath returns the module path declared by the go.mod of the directory, or an empty string.
+func goModulePath(dir string) string {
+	goMod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	if match := goModModuleRegex.FindSubmatch(goMod); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// parseGoTestOutput returns the logged lines of the failed tests, with and without -v.
+// With -v the log of a test precedes its failure, otherwise it follows it.
+// go test logs the base name of the test files, they are resolved to the module root by the package of the tests,
+// which is printed after the output of the package's tests.
+func parseGoTestOutput(output, modulePath string) []diagnostic {
+	var diagnostics []diagnostic
+	var order []string
+	logs := map[string][]diagnostic{}
+	failed := map[string]bool{}
+	flushPackage := func(pkg string) {
+		dir := ""
+		if modulePath != "" && (pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")) {
+			dir = strings.TrimPrefix(strings.TrimPrefix(pkg, modulePath), "/")
+		}
+		for _, test := range order {
+			if !failed[test] {
+				continue
+			}
+			for _, d := range logs[test] {
+				d.File = path.Join(dir, d.File)
+				diagnostics = append(diagnostics, d)
+			}
+		}
+		order, logs, failed = nil, map[string][]diagnostic{}, map[string]bool{}
+	}
+
+	var currentTest string
+	for _, line := range strings.Split(ansiEscapeRegex.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if match := goTestRunRegex.FindStringSubmatch(line); match != nil {
+			currentTest = match[1]
+			continue
+		}
+		if match := goTestFailRegex.FindStringSubmatch(line); match != nil {
+			currentTest = match[1]
+			failed[currentTest] = true
+			continue
+		}
+		if match := goTestPackageRegex.FindStringSubmatch(line); match != nil {
+			flushPackage(match[1])
+			currentTest = ""
+			continue
+		}
+		match := goTestLogRegex.FindStringSubmatch(line)
+		if match == nil || currentTest == "" {
+			continue
+		}
+
+		if _, ok := logs[currentTest]; !ok {
+			order = append(order, currentTest)
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		logs[currentTest] = append(logs[currentTest], diagnostic{
+			Check:    checkGoTest,
+			Rule:     currentTest,
+			File:     match[1],
+			Line:     lineNumber,
+			Severity: severityError,
+			Message:  match[3],
+		})
+	}
+	// The package is unknown, if the output is cut
+	flushPackage("")
+
+	return diagnostics
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_diagnostic_String(t *testing.T) {
+	tests := []struct {
+		name string
+		d    diagnostic
+		want string
+	}{
+		{
+			"Line and column",
+			diagnostic{Rule: "url", File: "step.yml", Line: 3, Column: 10, Severity: severityError, Message: "invalid URL"},
+			"step.yml:3:10: error: invalid URL (url)",
+		},
+		{
+			"Line only",
+			diagnostic{Rule: "TestMain", File: "main_test.go", Line: 12, Severity: severityError, Message: "failed"},
+			"main_test.go:12: error: failed (TestMain)",
+		},
+		{
+			"Whole file",
+			diagnostic{File: "README.md", Severity: severityWarning, Message: "out of date"},
+			"README.md: warning: out of date",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseGolangciLintOutput(t *testing.T) {
+	output := "\x1b[1mmain.go\x1b[0m:12:5: \x1b[31mineffectual assignment to err\x1b[0m (ineffassign)\n" +
+		"\terr = run()\n" +
+		"\t^\n" +
+		"pkg/step/step.go:40: File is not `gofmt`-ed with `-s` (gofmt)\n" +
+		"level=info msg=\"[runner] linters took 1s\"\n" +
+		"2 issues:\n"
+
+	want := []diagnostic{
+		{Check: checkGolangciLint, Rule: "ineffassign", File: "main.go", Line: 12, Column: 5, Severity: severityError, Message: "ineffectual assignment to err"},
+		{Check: checkGolangciLint, Rule: "gofmt", File: "pkg/step/step.go", Line: 40, Severity: severityError, Message: "File is not `gofmt`-ed with `-s`"},
+	}
+	if got := parseGolangciLintOutput(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGolangciLintOutput() got = %#v, want %#v", got, want)
+	}
+}
+
+func Test_parseGoTestOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []diagnostic
+	}{
+		{
+			"Verbose",
+			"=== RUN   TestA\n" +
+				"    a_test.go:10: got 1, want 2\n" +
+				"--- FAIL: TestA (0.00s)\n" +
+				"=== RUN   TestB\n" +
+				"    b_test.go:20: only logged\n" +
+				"--- PASS: TestB (0.00s)\n" +
+				"FAIL\n",
+			[]diagnostic{
+				{Check: checkGoTest, Rule: "TestA", File: "a_test.go", Line: 10, Severity: severityError, Message: "got 1, want 2"},
+			},
+		},
+		{
+			"Not verbose, with subtests",
+			"--- FAIL: TestA (0.00s)\n" +
+				"    --- FAIL: TestA/case (0.00s)\n" +
+				"        a_test.go:15: unexpected error\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/bitrise-steplib/steps-example\t0.012s\n",
+			[]diagnostic{
+				{Check: checkGoTest, Rule: "TestA/case", File: "a_test.go", Line: 15, Severity: severityError, Message: "unexpected error"},
+			},
+		},
+		{
+			"Multiple packages",
+			"ok  \tgithub.com/bitrise-steplib/steps-example\t0.010s\n" +
+				"--- FAIL: TestA (0.00s)\n" +
+				"    a_test.go:10: failed in pkg\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/bitrise-steplib/steps-example/internal/pkg\t0.012s\n" +
+				"--- FAIL: TestA (0.00s)\n" +
+				"    a_test.go:20: failed in other\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/bitrise-steplib/steps-example/other\t0.011s\n" +
+				"FAIL\n",
+			[]diagnostic{
+				{Check: checkGoTest, Rule: "TestA", File: "internal/pkg/a_test.go", Line: 10, Severity: severityError, Message: "failed in pkg"},
+				{Check: checkGoTest, Rule: "TestA", File: "other/a_test.go", Line: 20, Severity: severityError, Message: "failed in other"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGoTestOutput(tt.output, "github.com/bitrise-steplib/steps-example"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGoTestOutput() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_goModulePath(t *testing.T) {
+	dir := t.TempDir()
+	if got := goModulePath(dir); got != "" {
+		t.Errorf("goModulePath() without go.mod got = %s, want empty", got)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("// Comment\nmodule github.com/bitrise-steplib/steps-example\n\ngo 1.16\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := goModulePath(dir), "github.com/bitrise-steplib/steps-example"; got != want {
+		t.Errorf("goModulePath() got = %s, want %s", got, want)
+	}
+}
+
+func Test_sortDiagnostics(t *testing.T) {
+	diagnostics := []diagnostic{
+		{Check: checkYAMLLint, File: "step.yml", Line: 3},
+		{Check: checkAudit, File: "step.yml", Line: 3},
+		{Check: checkREADMEDiff, File: "README.md"},
+		{Check: checkAudit, File: "step.yml", Line: 1},
+	}
+	sortDiagnostics(diagnostics)
+
+	want := []diagnostic{
+		{Check: checkREADMEDiff, File: "README.md"},
+		{Check: checkAudit, File: "step.yml", Line: 1},
+		{Check: checkAudit, File: "step.yml", Line: 3},
+		{Check: checkYAMLLint, File: "step.yml", Line: 3},
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("sortDiagnostics() got = %#v, want %#v", diagnostics, want)
+	}
+}
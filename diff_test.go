@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func Test_unifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			"Equal",
+			"a\nb\n",
+			"a\nb\n",
+			"",
+		},
+		{
+			"Single change with context",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			`--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			"Separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			`--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`,
+		},
+		{
+			"Missing newline at end of file",
+			"a\n",
+			"a",
+			`--- a
++++ b
+@@ -1 +1 @@
+-a
++a
+\ No newline at end of file
+`,
+		},
+		{
+			"Insertion into empty text",
+			"",
+			"a\n",
+			`--- a
++++ b
+@@ -0,0 +1 @@
++a
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", tt.from, tt.to); got != tt.want {
+				t.Errorf("unifiedDiff() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_firstChangedLine(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want int
+	}{
+		{"Equal", "a\nb\n", "a\nb\n", 0},
+		{"Changed line", "a\nb\nc\n", "a\nB\nc\n", 2},
+		{"Added line", "a\nb\n", "a\nb\nc\n", 3},
+		{"Missing new line", "a\nb", "a\nb\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstChangedLine(tt.from, tt.to); got != tt.want {
+				t.Errorf("firstChangedLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffLines_large(t *testing.T) {
+	// The LCS table of the whole texts would take 20000*20000 ints
+	var from, to []string
+	for i := 0; i < 20000; i++ {
+		from = append(from, strconv.Itoa(i))
+		to = append(to, strconv.Itoa(i))
+	}
+	to[10000] = "changed"
+
+	ops := diffLines(from, to)
+	var changes []diffOp
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, op)
+		}
+	}
+	if want := []diffOp{{'-', "10000"}, {'+', "changed"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("diffLines() changes = %v, want %v", changes, want)
+	}
+	if len(ops) != 20001 {
+		t.Errorf("diffLines() returned %d ops, want 20001", len(ops))
+	}
+}
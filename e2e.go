@@ -1,10 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,92 +30,399 @@ type partialBitriseModel struct {
 	Workflows yaml.MapSlice `json:"workflows,omitempty" yaml:"workflows,omitempty"`
 }
 
-func runE2E(commandFactory command.Factory, workDir string, shouldFailOnFirstError bool, segmentKey string, parentURL string) error {
+type e2eOptions struct {
+	FailurePolicy e2eFailurePolicy
+	// Analytics is the client of the ci_e2e_finished events, no events are sent if nil.
+	Analytics           analytics.Client
+	AnalyticsContext    analyticsContext
+	ParentURL           string
+	DiffPreviousRelease bool
+	UpdateSnapshots     bool
+	Shuffle             bool
+	ShuffleSeed         string
+	SecretsEnvPrefix    string
+	RequiredSecrets     []string
+	ReproDir            string
+	// DurationBaselinePath is the JSON file of the recent E2E test durations, regressions are not checked if empty.
+	DurationBaselinePath    string
+	DurationThreshold       float64
+	DurationRegressionFails bool
+	// Include and Exclude select the E2E tests by their name, all of them run if both are empty.
+	Include []string
+	Exclude []string
+	// TestTimeout is the maximum duration of an E2E test, no timeout is applied if 0.
+	TestTimeout time.Duration
+}
+
+// e2eTest is a single E2E test case, either a `test_` workflow of the E2E bitrise.yml or a declarative test case.
+type e2eTest struct {
+	Name string
+	Run  func() (e2eResourceUsage, error)
+	// Compare runs after Run, like the run of the previous release. Its time is not part of the test's duration.
+	Compare func()
+}
+
+// runE2E runs the E2E tests and returns the duration of the run, without the time spent on comparing
+// the tests with the previous release.
+func runE2E(commandFactory command.Factory, workDir string, opts e2eOptions) (time.Duration, error) {
+	start := time.Now()
+	comparing, err := runE2ETests(commandFactory, workDir, opts)
+	return time.Since(start) - comparing, err
+}
+
+// runE2ETests runs the E2E tests and returns the time spent on comparing the tests with the previous release.
+func runE2ETests(commandFactory command.Factory, workDir string, opts e2eOptions) (time.Duration, error) {
+	var comparing time.Duration
+
 	e2eBitriseYMLPath := filepath.Join(workDir, "e2e", "bitrise.yml")
-	if exists, err := pathutil.IsPathExists(e2eBitriseYMLPath); err != nil {
-		return err
-	} else if !exists {
-		return fmt.Errorf("looking for bitrise.yml in e2e directory, path (%s) does not exists", e2eBitriseYMLPath)
+	hasBitriseYML, err := pathutil.IsPathExists(e2eBitriseYMLPath)
+	if err != nil {
+		return 0, err
 	}
 
-	log.Infof("Using bitrise.yml from: %s", e2eBitriseYMLPath)
+	e2eCasesPath := filepath.Join(workDir, "e2e", e2eCasesFileName)
+	hasCases, err := pathutil.IsPathExists(e2eCasesPath)
+	if err != nil {
+		return 0, err
+	}
 
-	secrets, err := lookupSecrets(workDir)
+	if !hasBitriseYML && !hasCases {
+		return 0, fmt.Errorf("looking for bitrise.yml or %s in e2e directory, paths (%s, %s) do not exist", e2eCasesFileName, e2eBitriseYMLPath, e2eCasesPath)
+	}
+
+	secrets, cleanupSecrets, err := prepareE2ESecrets(workDir, opts)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	defer cleanupSecrets()
 
-	if secrets == "" {
-		log.Errorf("No %s found", defaultBitriseSecretsName)
-	} else {
-		log.Infof("Using secrets from: %s", secrets)
+	mockRoutesPath := filepath.Join(workDir, "e2e", mockServerRoutesFileName)
+	var mock *mockServer
+	if exists, err := pathutil.IsPathExists(mockRoutesPath); err != nil {
+		return 0, err
+	} else if exists {
+		mock, err = startMockServer(mockRoutesPath)
+		if err != nil {
+			return 0, err
+		}
+		defer mock.stop()
+
+		// Exposed to every `bitrise run` through the inherited environment
+		if err := os.Setenv(mockServerURLEnvKey, mock.url); err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err := os.Unsetenv(mockServerURLEnvKey); err != nil {
+				log.Warnf("Failed to unset %s: %s", mockServerURLEnvKey, err)
+			}
+		}()
+
+		log.Infof("Mock server started at %s ($%s), routes from: %s", mock.url, mockServerURLEnvKey, mockRoutesPath)
+	}
+
+	var tests []e2eTest
+	var differ *e2eDiffer
+	var snapshotter *e2eSnapshotter
+	if hasBitriseYML {
+		log.Infof("Using bitrise.yml from: %s", e2eBitriseYMLPath)
+
+		workflows, err := readE2EWorkflows(e2eBitriseYMLPath)
+		if err != nil {
+			return 0, err
+		}
+
+		configBytes, err := ioutil.ReadFile(e2eBitriseYMLPath)
+		if err != nil {
+			return 0, err
+		}
+
+		snapshotConfigs, err := readE2ESnapshotConfigsFromBytes(configBytes)
+		if err != nil {
+			return 0, err
+		}
+
+		var observer *e2eObserver
+		if opts.DiffPreviousRelease || len(snapshotConfigs) > 0 {
+			stepOutputKeys, err := readStepOutputKeys(filepath.Join(workDir, "step.yml"))
+			if err != nil {
+				return 0, err
+			}
+
+			outputKeys := append([]string{}, stepOutputKeys...)
+			for _, config := range snapshotConfigs {
+				outputKeys = append(outputKeys, config.Outputs...)
+			}
+
+			observer, err = newE2EObserver(workDir, e2eBitriseYMLPath, secrets, outputKeys)
+			if err != nil {
+				return 0, err
+			}
+			observer.timeout = opts.TestTimeout
+			defer observer.cleanup()
+
+			if len(snapshotConfigs) > 0 {
+				snapshotter = newE2ESnapshotter(workDir, stepOutputKeys, opts.UpdateSnapshots)
+			}
+		}
+		if opts.UpdateSnapshots {
+			if snapshotter == nil {
+				// No workflow has snapshots anymore, all golden files are stale
+				snapshotter = newE2ESnapshotter(workDir, nil, true)
+			}
+			if err := snapshotter.removeStaleWorkflows(snapshotConfigs); err != nil {
+				return 0, err
+			}
+		}
+
+		if opts.DiffPreviousRelease {
+			differ, err = newE2EDiffer(commandFactory, observer, workDir)
+			if err != nil {
+				return 0, err
+			}
+
+			log.Infof("Comparing E2E behaviour with previous release: %s", differ.previousTag)
+		}
+
+		for _, workflow := range workflows {
+			workflow := workflow
+			snapshotConfig, hasSnapshot := snapshotConfigs[workflow]
+			run := func() (e2eResourceUsage, error) {
+				return runE2EWorkflow(workDir, e2eBitriseYMLPath, secrets, workflow, opts.TestTimeout)
+			}
+			test := e2eTest{Name: workflow, Run: run}
+			if differ != nil || hasSnapshot {
+				var current *e2eRunResult
+				test.Run = func() (e2eResourceUsage, error) {
+					var err error
+					current, err = observer.observe(workflow, "path::"+workDir, currentRunConfigName)
+					if current == nil {
+						return e2eResourceUsage{}, err
+					}
+					if hasSnapshot {
+						if snapshotErr := snapshotter.check(workflow, snapshotConfig, *current); snapshotErr != nil && err == nil {
+							err = snapshotErr
+						}
+					}
+					return current.Usage, err
+				}
+				if differ != nil {
+					test.Compare = func() {
+						if current != nil {
+							mock.withoutRecording(func() { differ.compare(workflow, *current) })
+						}
+					}
+				}
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	if hasCases {
+		log.Infof("Using test cases from: %s", e2eCasesPath)
+
+		caseRunner, err := newE2ECaseRunner(workDir, e2eCasesPath, secrets)
+		if err != nil {
+			return 0, err
+		}
+		defer caseRunner.cleanup()
+		caseRunner.timeout = opts.TestTimeout
+
+		for _, c := range caseRunner.cases {
+			c := c
+			tests = append(tests, e2eTest{Name: c.workflowName(), Run: func() (e2eResourceUsage, error) {
+				return caseRunner.run(c)
+			}})
+		}
 	}
 
-	workflows, err := readE2EWorkflows(e2eBitriseYMLPath)
+	tests, deselected := selectE2ETests(tests, opts.Include, opts.Exclude)
+	if len(deselected) > 0 {
+		log.Infof("Skipping %d E2E test(s) not selected by %s: %s", len(deselected), repoConfigFileName, strings.Join(deselected, ", "))
+	}
+
+	if opts.Shuffle {
+		seed, err := e2eShuffleSeed(opts.ShuffleSeed)
+		if err != nil {
+			return 0, err
+		}
+		shuffleE2ETests(tests, seed)
+
+		log.Infof("Shuffled E2E test order with seed %d, set e2e_shuffle_seed: \"%d\" to reproduce this order", seed, seed)
+	}
+
+	quarantine, err := readQuarantine(filepath.Join(workDir, "e2e", quarantineFileName), time.Now())
 	if err != nil {
-		return err
+		return 0, err
+	}
+	for _, entry := range quarantine.expired {
+		log.Errorf("Quarantine of '%s' expired on %s (owner: %s), fix the test or extend the quarantine", entry.Workflow, entry.Expires, entry.Owner)
 	}
 
-	shouldSendAnalytics := parentURL != "" && segmentKey != ""
-	var client analytics.Client
-	if shouldSendAnalytics {
-		client = analytics.New(segmentKey)
-		defer client.Close()
+	var durations *durationBaseline
+	if opts.DurationBaselinePath != "" {
+		durations, err = readDurationBaseline(opts.DurationBaselinePath)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	var result string
-	success := true
-	for _, workflow := range workflows {
+	var quarantined string
+	var stopErr error
+	var regressions []durationRegression
+	failures := 0
+	success := len(quarantine.expired) == 0
+	for i, test := range tests {
+		mock.startTest(test.Name)
 		start := time.Now()
-		err = runE2EWorkflow(commandFactory, workDir, e2eBitriseYMLPath, secrets, workflow)
-		elapsed := time.Since(start).Milliseconds()
+		usage, err := test.Run()
+		elapsed := time.Since(start)
+		if test.Compare != nil {
+			compareStart := time.Now()
+			test.Compare()
+			comparing += time.Since(compareStart)
+		}
 
-		if shouldSendAnalytics {
-			if err := sendAnalytics(client, workflow, err == nil, parentURL, elapsed); err != nil {
-				return err
+		if opts.Analytics != nil {
+			event := e2eAnalyticsEvent{Workflow: test.Name, Err: err, Duration: elapsed, Usage: usage}
+			if err := sendAnalytics(opts.Analytics, opts.AnalyticsContext, opts.ParentURL, event); err != nil {
+				return comparing, err
 			}
 		}
 
-		if err != nil {
-			if shouldFailOnFirstError {
-				return fmt.Errorf("'%s' E2E test failed: %w", workflow, err)
+		if durations != nil && err == nil {
+			if regression := durations.check(test.Name, elapsed, opts.DurationThreshold); regression != nil {
+				regressions = append(regressions, *regression)
+			}
+			durations.record(test.Name, elapsed)
+		}
+
+		if entry, ok := quarantine.entry(test.Name); ok {
+			status := colorstring.Green("OK")
+			if err != nil {
+				status = colorstring.Yellow("FAIL") + ": " + err.Error()
 			}
+			quarantined += fmt.Sprintf("- %s (%s) \n    %s\n    %s\n", test.Name, status, entry, usage.summary(elapsed))
 
+			continue
+		}
+
+		if err != nil {
+			failures++
 			success = false
-			result += fmt.Sprintf("- %s (FAIL): %s \n", colorstring.Red(workflow), err)
+			result += fmt.Sprintf("- %s (FAIL): %s \n", colorstring.Red(test.Name), err)
+			result += fmt.Sprintf("    %s\n", usage.summary(elapsed))
+			if reproPath := writeReproForError(opts.ReproDir, test.Name, err); reproPath != "" {
+				result += fmt.Sprintf("    reproduce with: %s\n", reproPath)
+			}
+
+			if opts.FailurePolicy.shouldStop(failures) {
+				stopErr = fmt.Errorf("'%s' E2E test failed: %w", test.Name, err)
+				for _, skipped := range tests[i+1:] {
+					result += fmt.Sprintf("- %s (SKIPPED) \n", colorstring.Yellow(skipped.Name))
+				}
+				log.Errorf("Stopping E2E tests after %d failure(s), failure policy: %s", failures, opts.FailurePolicy)
+
+				break
+			}
 
 			continue
 		}
 
-		result += fmt.Sprintf("- %s (OK) \n", colorstring.Green(workflow))
+		result += fmt.Sprintf("- %s (OK) \n", colorstring.Green(test.Name))
+		result += fmt.Sprintf("    %s\n", usage.summary(elapsed))
 	}
 
 	log.Infof("Step E2E summary:")
 	log.Printf("%s", result)
+	if quarantined != "" {
+		log.Infof("Quarantined E2E tests (not failing the build):")
+		log.Printf("%s", quarantined)
+	}
+	if len(quarantine.expired) > 0 {
+		log.Infof("Expired quarantine entries:")
+		for _, entry := range quarantine.expired {
+			log.Printf("- %s (%s)", colorstring.Red(entry.Workflow), entry)
+		}
+	}
+	if differ != nil {
+		log.Infof("Behaviour diff against %s:", differ.previousTag)
+		log.Printf("%s", differ.summary())
+	}
+	if snapshotter != nil {
+		log.Infof("Snapshots:")
+		log.Printf("%s", snapshotter.summary())
+	}
+	if durations != nil {
+		if err := durations.write(opts.DurationBaselinePath); err != nil {
+			log.Warnf("Failed to update the duration baseline: %s", err)
+		}
+
+		log.Infof("Duration regressions (threshold: %g%%):", opts.DurationThreshold)
+		for _, regression := range regressions {
+			if opts.DurationRegressionFails {
+				success = false
+				log.Printf("- %s", colorstring.Red(regression))
+			} else {
+				log.Printf("- %s", colorstring.Yellow(regression))
+			}
+		}
+		if len(regressions) == 0 {
+			log.Printf("No regressions")
+		}
+	}
+	if mock != nil {
+		log.Infof("Mock server requests:")
+		if failures := mock.verify(); len(failures) > 0 {
+			success = false
+			for _, failure := range failures {
+				log.Printf("- %s", colorstring.Red(failure))
+			}
+		} else {
+			log.Printf("All expectations met")
+		}
+	}
+	if stopErr != nil {
+		return comparing, stopErr
+	}
 	if !success {
-		return fmt.Errorf("E2E tests failed")
+		return comparing, fmt.Errorf("E2E tests failed")
 	}
 
-	return nil
+	return comparing, nil
 }
 
-func sendAnalytics(client analytics.Client, workflow string, success bool, parentURL string, duration int64) error {
+// e2eAnalyticsEvent is the result of an E2E test run, sent as a ci_e2e_finished event.
+type e2eAnalyticsEvent struct {
+	Workflow string
+	Err      error
+	Duration time.Duration
+	Usage    e2eResourceUsage
+}
+
+func sendAnalytics(client analytics.Client, context analyticsContext, parentURL string, event e2eAnalyticsEvent) error {
 	var status string
-	if success {
+	if event.Err == nil {
 		status = "success"
 	} else {
 		status = "error"
 	}
+
+	properties := context.properties()
+	properties["workflow"] = event.Workflow
+	properties["status"] = status
+	properties["failure_category"] = failureCategory(event.Err)
+	properties["parent_url"] = parentURL
+	properties["stack_id"] = os.Getenv("BITRISEIO_STACK_ID")
+	properties["duration"] = event.Duration.Milliseconds()
+	properties["user_cpu"] = event.Usage.UserCPU.Milliseconds()
+	properties["system_cpu"] = event.Usage.SystemCPU.Milliseconds()
+	properties["max_rss"] = event.Usage.MaxRSS
+
 	if err := client.Enqueue(analytics.Track{
-		UserId: unifiedCiAppID,
-		Event:  "ci_e2e_finished",
-		Properties: map[string]interface{}{
-			"workflow":   workflow,
-			"status":     status,
-			"parent_url": parentURL,
-			"stack_id":   os.Getenv("BITRISEIO_STACK_ID"),
-			"duration":   duration,
-		},
+		UserId:     unifiedCiAppID,
+		Event:      "ci_e2e_finished",
+		Properties: properties,
 	}); err != nil {
 		return err
 	}
@@ -145,32 +455,172 @@ func readE2EWorkflowsFromBytes(configBytes []byte) ([]string, error) {
 	return result, nil
 }
 
-func runE2EWorkflow(commandFactory command.Factory, workDir string, configPath string, secretsPath string, workflow string) error {
+// runE2EWorkflow runs the workflow in a `bitrise` child process, and returns the resource usage of its process tree.
+// The workflow is killed if it runs longer than the timeout (if not 0). A failed run's error describes how to
+// reproduce it.
+func runE2EWorkflow(workDir string, configPath string, secretsPath string, workflow string, timeout time.Duration) (e2eResourceUsage, error) {
 	e2eCmdArgs := []string{"run", "--config", configPath}
 	if secretsPath != "" {
 		e2eCmdArgs = append(e2eCmdArgs, "--inventory", secretsPath)
 	}
 	e2eCmdArgs = append(e2eCmdArgs, workflow)
 
-	e2eCmd := commandFactory.Create(
-		"bitrise",
-		e2eCmdArgs,
-		&command.Opts{
-			Dir:    workDir,
-			Stdin:  os.Stdin,
-			Stdout: os.Stdout,
-		})
+	// command.Command does not expose the process state, which holds the resource usage
+	e2eCmd := exec.Command("bitrise", e2eCmdArgs...)
+	e2eCmd.Dir = workDir
+	e2eCmd.Stdin = os.Stdin
+	e2eCmd.Stdout = os.Stdout
 	fmt.Println()
-	log.Donef("$ %s", e2eCmd.PrintableCommandArgs())
+	log.Donef("$ %s", printableCommandArgs(e2eCmd.Args))
+
+	err := runWithTimeout(e2eCmd, timeout)
+	usage := processResourceUsage(e2eCmd.ProcessState)
+	if err == nil {
+		return usage, nil
+	}
+
+	if !errorutil.IsExitStatusError(err) {
+		err = fmt.Errorf("failed to run command: %v", err)
+	}
+
+	script, scriptErr := e2eReproScript(workflow, workDir, configPath, secretsPath, workflow)
+	if scriptErr != nil {
+		log.Warnf("Failed to create reproduction script: %s", scriptErr)
+		return usage, err
+	}
+	return usage, withRepro(err, script)
+}
 
-	if err := e2eCmd.Run(); err != nil {
-		if errorutil.IsExitStatusError(err) {
-			return err
+// processWaitDelay is how long the output of a finished command is waited for, which its orphaned child processes
+// may hold open.
+const processWaitDelay = 10 * time.Second
+
+// runWithTimeout runs the command, and kills it with its child processes if it doesn't finish within the timeout.
+// No timeout is applied if 0. A killed command's error is not an exit status error.
+// With a timeout, the command runs in its own process group without stdin, SIGINT and SIGTERM are forwarded to it.
+func runWithTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	if timeout <= 0 {
+		return cmd.Run()
+	}
+
+	setProcessGroup(cmd)
+	// The new process group can not read the terminal
+	cmd.Stdin = nil
+	cmd.WaitDelay = processWaitDelay
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	stopForwarding := forwardSignals(cmd)
+	timer := time.AfterFunc(timeout, func() {
+		if err := killProcessGroup(cmd); err != nil {
+			log.Warnf("Failed to kill %s: %s", cmd.Path, err)
 		}
+	})
+	err := cmd.Wait()
+	if sig := stopForwarding(); sig != nil {
+		// The run was interrupted, terminate like the command did
+		if err := raiseSignal(sig); err != nil {
+			log.Warnf("Failed to re-raise %s: %s", sig, err)
+		}
+	}
+	if !timer.Stop() {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		log.Warnf("%s succeeded, but its child processes kept its output open", cmd.Path)
+		return nil
+	}
+	return err
+}
 
-		return fmt.Errorf("failed to run command: %v", err)
+// exitCodeOf returns the exit code of a command's error, if it failed with a non-zero exit status.
+func exitCodeOf(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
 	}
-	return nil
+	return 0, false
+}
+
+// prepareE2ESecrets returns the path of the validated secrets inventory.
+// The returned cleanup function removes the temporary inventory, if one was created.
+func prepareE2ESecrets(workDir string, opts e2eOptions) (string, func(), error) {
+	secrets, cleanup, err := resolveE2ESecrets(workDir, opts)
+	if err != nil || secrets == "" {
+		return secrets, cleanup, err
+	}
+
+	inventory, err := ioutil.ReadFile(secrets)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	var references []string
+	for _, name := range []string{"bitrise.yml", e2eCasesFileName} {
+		content, err := ioutil.ReadFile(filepath.Join(workDir, "e2e", name))
+		if err != nil && !os.IsNotExist(err) {
+			cleanup()
+			return "", nil, err
+		}
+		references = append(references, string(content))
+	}
+
+	warnings, err := validateSecretsInventory(inventory, references)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("invalid secrets inventory: %w", err)
+	}
+	for _, warning := range warnings {
+		log.Warnf("Secrets inventory: %s", warning)
+	}
+
+	return secrets, cleanup, nil
+}
+
+// resolveE2ESecrets returns the path of the secrets inventory, looked up in this order:
+// plaintext inventory, encrypted inventory, inventory generated from prefixed env vars.
+// The returned cleanup function removes the temporary inventory created for the latter two.
+func resolveE2ESecrets(workDir string, opts e2eOptions) (string, func(), error) {
+	noCleanup := func() {}
+
+	secrets, err := lookupSecrets(workDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if secrets != "" {
+		log.Infof("Using secrets from: %s", secrets)
+		return secrets, noCleanup, nil
+	}
+
+	encryptedSecrets, err := lookupEncryptedSecrets(workDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if encryptedSecrets != "" {
+		secrets, cleanup, err := decryptSecretsToTemp(encryptedSecrets, os.Getenv(secretsKeyEnvKey))
+		if err != nil {
+			return "", nil, err
+		}
+		log.Infof("Using encrypted secrets from: %s", encryptedSecrets)
+		return secrets, cleanup, nil
+	}
+
+	if opts.SecretsEnvPrefix != "" {
+		inventory, keys, err := secretsInventoryFromEnv(os.Environ(), opts.SecretsEnvPrefix, opts.RequiredSecrets)
+		if err != nil {
+			return "", nil, err
+		}
+		secrets, cleanup, err := writeSecretsToTemp(inventory)
+		if err != nil {
+			return "", nil, err
+		}
+		log.Infof("Using secrets from env vars with %s prefix: %s", opts.SecretsEnvPrefix, strings.Join(keys, ", "))
+		return secrets, cleanup, nil
+	}
+
+	log.Errorf("No %s or %s found", defaultBitriseSecretsName, encryptedSecretsFileName)
+	return "", noCleanup, nil
 }
 
 func lookupSecrets(workDir string) (string, error) {
@@ -188,3 +638,15 @@ func lookupSecrets(workDir string) (string, error) {
 
 	return "", nil
 }
+
+// printableCommandArgs mirrors command.Command's PrintableCommandArgs for commands created with os/exec.
+func printableCommandArgs(args []string) string {
+	var decorated []string
+	for i, arg := range args {
+		if i > 0 {
+			arg = strconv.Quote(arg)
+		}
+		decorated = append(decorated, arg)
+	}
+	return strings.Join(decorated, " ")
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"gopkg.in/yaml.v2"
+)
+
+const e2eCasesFileName = "cases.yml"
+
+var errExpectationFailed = errors.New("expectation failed")
+
+// e2eCaseNameRegexp restricts the case names, which are used in file paths and workflow IDs.
+var e2eCaseNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// e2eCasesModel is the declarative E2E test format, stored in e2e/cases.yml:
+//
+//	cases:
+//	- name: pem_format_key
+//	  envs:
+//	    KEY_PATH: ./testsave/key
+//	  inputs:
+//	    ssh_rsa_private_key: $PEM_FORMAT_SSH_PRIVATE_KEY
+//	  expect:
+//	    exit_code: 0
+//	    outputs:
+//	      SSH_AUTH_SOCK:
+//	        matches: ^/.+
+//	    files:
+//	    - path: ./testsave/key
+type e2eCasesModel struct {
+	Cases []e2eCase `yaml:"cases"`
+}
+
+type e2eCase struct {
+	Name   string            `yaml:"name"`
+	Envs   map[string]string `yaml:"envs"`
+	Inputs map[string]string `yaml:"inputs"`
+	Expect e2eCaseExpect     `yaml:"expect"`
+}
+
+type e2eCaseExpect struct {
+	ExitCode int                        `yaml:"exit_code"`
+	Outputs  map[string]e2eValueMatcher `yaml:"outputs"`
+	Files    []e2eFileExpect            `yaml:"files"`
+}
+
+// e2eValueMatcher matches a value either exactly (equals) or by a regular expression (matches).
+type e2eValueMatcher struct {
+	Equals  *string `yaml:"equals"`
+	Matches string  `yaml:"matches"`
+	// matchesRegexp is the compiled Matches pattern, set when the test cases are read.
+	matchesRegexp *regexp.Regexp
+}
+
+// e2eFileExpect describes a file the step is expected to create (or not to create, if Exists is false).
+// Relative paths are resolved from the working directory of the test case.
+type e2eFileExpect struct {
+	Path            string `yaml:"path"`
+	Exists          *bool  `yaml:"exists"`
+	e2eValueMatcher `yaml:",inline"`
+}
+
+func (c e2eCase) workflowName() string {
+	return "case_" + c.Name
+}
+
+func (m e2eValueMatcher) match(value string) error {
+	if m.Equals != nil && value != *m.Equals {
+		return fmt.Errorf("expected %q, got %q", *m.Equals, value)
+	}
+	if m.Matches != "" {
+		re := m.matchesRegexp
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(m.Matches); err != nil {
+				return fmt.Errorf("invalid pattern (%s): %v", m.Matches, err)
+			}
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("expected to match %q, got %q", m.Matches, value)
+		}
+	}
+	return nil
+}
+
+// compile compiles the Matches pattern, so an invalid one fails before the workflow runs.
+func (m *e2eValueMatcher) compile() error {
+	if m.Matches == "" {
+		return nil
+	}
+	re, err := regexp.Compile(m.Matches)
+	if err != nil {
+		return fmt.Errorf("invalid pattern (%s): %v", m.Matches, err)
+	}
+	m.matchesRegexp = re
+	return nil
+}
+
+func readE2ECasesFromBytes(casesBytes []byte) ([]e2eCase, error) {
+	var model e2eCasesModel
+	if err := yaml.UnmarshalStrict(casesBytes, &model); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, c := range model.Cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("test case without name")
+		}
+		if !e2eCaseNameRegexp.MatchString(c.Name) {
+			return nil, fmt.Errorf("invalid test case name: %s, only letters, digits, _ and - are allowed", c.Name)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate test case: %s", c.Name)
+		}
+		names[c.Name] = true
+
+		for key, matcher := range c.Expect.Outputs {
+			if matcher.Equals == nil && matcher.Matches == "" {
+				return nil, fmt.Errorf("test case %s: no expectation set for output %s", c.Name, key)
+			}
+			if err := matcher.compile(); err != nil {
+				return nil, fmt.Errorf("test case %s: output %s: %w", c.Name, key, err)
+			}
+			c.Expect.Outputs[key] = matcher
+		}
+		for i, file := range c.Expect.Files {
+			if file.Path == "" {
+				return nil, fmt.Errorf("test case %s: file expectation without path", c.Name)
+			}
+			if err := c.Expect.Files[i].compile(); err != nil {
+				return nil, fmt.Errorf("test case %s: file %s: %w", c.Name, file.Path, err)
+			}
+		}
+	}
+
+	return model.Cases, nil
+}
+
+// e2eCaseRunner compiles declarative test cases into temporary bitrise workflows calling the local step,
+// runs them, then checks the exit code, the exported outputs and the created files.
+type e2eCaseRunner struct {
+	workDir     string
+	secretsPath string
+	cases       []e2eCase
+	tmpDir      string
+	// timeout is the maximum duration of a case, no timeout is applied if 0.
+	timeout time.Duration
+}
+
+func newE2ECaseRunner(workDir, casesPath, secretsPath string) (*e2eCaseRunner, error) {
+	casesBytes, err := ioutil.ReadFile(casesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := readE2ECasesFromBytes(casesBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid test cases (%s): %w", casesPath, err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "e2e-cases")
+	if err != nil {
+		return nil, err
+	}
+
+	return &e2eCaseRunner{
+		workDir:     workDir,
+		secretsPath: secretsPath,
+		cases:       cases,
+		tmpDir:      tmpDir,
+	}, nil
+}
+
+func (r *e2eCaseRunner) cleanup() {
+	if err := os.RemoveAll(r.tmpDir); err != nil {
+		log.Warnf("Failed to remove temporary directory (%s): %s", r.tmpDir, err)
+	}
+}
+
+func (r *e2eCaseRunner) run(c e2eCase) (e2eResourceUsage, error) {
+	caseDir := filepath.Join(r.tmpDir, c.Name)
+	if err := os.MkdirAll(caseDir, 0700); err != nil {
+		return e2eResourceUsage{}, err
+	}
+
+	dumpPath := filepath.Join(r.tmpDir, c.Name+".env")
+	configBytes, err := compileE2ECase(c, "path::"+r.workDir, dumpPath)
+	if err != nil {
+		return e2eResourceUsage{}, err
+	}
+
+	configPath := filepath.Join(r.tmpDir, c.Name+".bitrise.yml")
+	if err := ioutil.WriteFile(configPath, configBytes, 0600); err != nil {
+		return e2eResourceUsage{}, err
+	}
+
+	exitCode := 0
+	usage, runErr := runE2EWorkflow(caseDir, configPath, r.secretsPath, c.workflowName(), r.timeout)
+	if runErr != nil {
+		var ok bool
+		if exitCode, ok = exitCodeOf(runErr); !ok {
+			updateRepro(runErr, func(script *reproScript) { r.updateReproScript(script, c, configPath) })
+			return usage, runErr
+		}
+	}
+
+	dumpBytes, err := ioutil.ReadFile(dumpPath)
+	if err != nil && !os.IsNotExist(err) {
+		return usage, err
+	}
+
+	var outputKeys []string
+	for key := range c.Expect.Outputs {
+		outputKeys = append(outputKeys, key)
+	}
+	outputs := parseEnvDump(dumpBytes, outputKeys)
+
+	failures := checkE2ECase(c.Expect, exitCode, outputs, caseDir)
+	if len(failures) > 0 {
+		failureErr := fmt.Errorf("%w: %s", errExpectationFailed, strings.Join(failures, ", "))
+		script, err := e2eReproScript(c.workflowName(), caseDir, configPath, r.secretsPath, c.workflowName())
+		if err != nil {
+			return usage, failureErr
+		}
+		r.updateReproScript(&script, c, configPath)
+		return usage, withRepro(failureErr, script)
+	}
+
+	return usage, nil
+}
+
+// updateReproScript makes the reproduction of the case independent of the temporary directory, removed by cleanup:
+// the case runs in a new directory, and its workflow does not dump the environment.
+func (r *e2eCaseRunner) updateReproScript(script *reproScript, c e2eCase, configPath string) {
+	script.TmpDir = true
+	if configBytes, err := compileE2ECase(c, "path::"+r.workDir, ""); err == nil {
+		script.replaceFile(configPath, string(configBytes))
+	}
+}
+
+// compileE2ECase turns a test case into a bitrise.yml with a single workflow, running the step under test.
+// The workflow dumps its environment into dumpPath, if it is not empty.
+func compileE2ECase(c e2eCase, stepRef, dumpPath string) ([]byte, error) {
+	var envs []interface{}
+	for _, key := range sortedKeys(c.Envs) {
+		envs = append(envs, yaml.MapSlice{{Key: key, Value: c.Envs[key]}})
+	}
+
+	stepModel := yaml.MapSlice{}
+	if len(c.Inputs) > 0 {
+		var inputs []interface{}
+		for _, key := range sortedKeys(c.Inputs) {
+			inputs = append(inputs, yaml.MapSlice{{Key: key, Value: c.Inputs[key]}})
+		}
+		stepModel = append(stepModel, yaml.MapItem{Key: "inputs", Value: inputs})
+	}
+
+	workflow := yaml.MapSlice{}
+	if len(envs) > 0 {
+		workflow = append(workflow, yaml.MapItem{Key: "envs", Value: envs})
+	}
+	steps := []interface{}{yaml.MapSlice{{Key: stepRef, Value: stepModel}}}
+	if dumpPath != "" {
+		steps = append(steps, dumpEnvStep(dumpPath))
+	}
+	workflow = append(workflow, yaml.MapItem{Key: "steps", Value: steps})
+
+	return yaml.Marshal(yaml.MapSlice{
+		{Key: "format_version", Value: "11"},
+		{Key: "default_step_lib_source", Value: "https://github.com/bitrise-io/bitrise-steplib.git"},
+		{Key: "workflows", Value: yaml.MapSlice{{Key: c.workflowName(), Value: workflow}}},
+	})
+}
+
+func checkE2ECase(expect e2eCaseExpect, exitCode int, outputs map[string]string, caseDir string) []string {
+	var failures []string
+	if exitCode != expect.ExitCode {
+		failures = append(failures, fmt.Sprintf("exit code: expected %d, got %d", expect.ExitCode, exitCode))
+	}
+
+	var outputKeys []string
+	for key := range expect.Outputs {
+		outputKeys = append(outputKeys, key)
+	}
+	sort.Strings(outputKeys)
+	for _, key := range outputKeys {
+		value, ok := outputs[key]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("output %s: not exported", key))
+			continue
+		}
+		if err := expect.Outputs[key].match(value); err != nil {
+			failures = append(failures, fmt.Sprintf("output %s: %s", key, err))
+		}
+	}
+
+	for _, file := range expect.Files {
+		pth := file.Path
+		if !filepath.IsAbs(pth) {
+			pth = filepath.Join(caseDir, pth)
+		}
+
+		content, err := ioutil.ReadFile(pth)
+		shouldExist := file.Exists == nil || *file.Exists
+		switch {
+		case os.IsNotExist(err) && shouldExist:
+			failures = append(failures, fmt.Sprintf("file %s: does not exist", file.Path))
+		case err == nil && !shouldExist:
+			failures = append(failures, fmt.Sprintf("file %s: should not exist", file.Path))
+		case err != nil && !os.IsNotExist(err):
+			failures = append(failures, fmt.Sprintf("file %s: %s", file.Path, err))
+		case err == nil:
+			if err := file.match(string(content)); err != nil {
+				failures = append(failures, fmt.Sprintf("file %s: %s", file.Path, err))
+			}
+		}
+	}
+
+	return failures
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_readE2ECasesFromBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		casesBytes []byte
+		want       []string
+		wantErr    bool
+	}{
+		{
+			"Valid cases",
+			[]byte(`cases:
+- name: pem_format_key
+  inputs:
+    ssh_rsa_private_key: $PEM_FORMAT_SSH_PRIVATE_KEY
+  expect:
+    outputs:
+      SSH_AUTH_SOCK:
+        matches: ^/.+
+    files:
+    - path: ./testsave/key
+      equals: key
+- name: invalid_key
+  expect:
+    exit_code: 1
+`),
+			[]string{"pem_format_key", "invalid_key"},
+			false,
+		},
+		{
+			"Duplicate case",
+			[]byte(`cases:
+- name: a
+- name: a
+`),
+			nil,
+			true,
+		},
+		{
+			"Name with path separator",
+			[]byte(`cases:
+- name: ../escape
+`),
+			nil,
+			true,
+		},
+		{
+			"Invalid output pattern",
+			[]byte(`cases:
+- name: a
+  expect:
+    outputs:
+      OUT:
+        matches: "("
+`),
+			nil,
+			true,
+		},
+		{
+			"Invalid file pattern",
+			[]byte(`cases:
+- name: a
+  expect:
+    files:
+    - path: out.txt
+      matches: "[a-"
+`),
+			nil,
+			true,
+		},
+		{
+			"Output without expectation",
+			[]byte(`cases:
+- name: a
+  expect:
+    outputs:
+      OUT: {}
+`),
+			nil,
+			true,
+		},
+		{
+			"Unknown field",
+			[]byte(`cases:
+- name: a
+  expected: {}
+`),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readE2ECasesFromBytes(tt.casesBytes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readE2ECasesFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var names []string
+			for _, c := range got {
+				names = append(names, c.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("readE2ECasesFromBytes() got = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkE2ECase(t *testing.T) {
+	caseDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(caseDir, "out.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	exact := "/path/to/file"
+	notExists := false
+	expect := e2eCaseExpect{
+		Outputs: map[string]e2eValueMatcher{
+			"EXACT": {Equals: &exact},
+			"REGEX": {Matches: `^\d+$`},
+		},
+		Files: []e2eFileExpect{
+			{Path: "out.txt", e2eValueMatcher: e2eValueMatcher{Matches: "^hel"}},
+			{Path: "missing.txt", Exists: &notExists},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		exitCode int
+		outputs  map[string]string
+		want     []string
+	}{
+		{
+			"Expectations met",
+			0,
+			map[string]string{"EXACT": "/path/to/file", "REGEX": "42"},
+			nil,
+		},
+		{
+			"Expectations not met",
+			1,
+			map[string]string{"EXACT": "/other"},
+			[]string{
+				"exit code: expected 0, got 1",
+				`output EXACT: expected "/path/to/file", got "/other"`,
+				"output REGEX: not exported",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkE2ECase(expect, tt.exitCode, tt.outputs, caseDir)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkE2ECase() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_e2eCaseRunner_reproAfterCleanup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the reproduction scripts are bash scripts")
+	}
+
+	// The fake bitrise records its working directory and config, then fails
+	binDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(binDir, "bitrise"), []byte(`#!/usr/bin/env bash
+pwd > "$FAKE_BITRISE_LOG"
+cat "$3" >> "$FAKE_BITRISE_LOG"
+exit 1
+`), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_BITRISE_LOG", filepath.Join(t.TempDir(), "run.log"))
+
+	casesPath := filepath.Join(t.TempDir(), e2eCasesFileName)
+	if err := ioutil.WriteFile(casesPath, []byte(`cases:
+- name: a
+  inputs:
+    key: value
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runner, err := newE2ECaseRunner("/step", casesPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, runErr := runner.run(runner.cases[0])
+	reproPath := writeReproForError(t.TempDir(), "case_a", runErr)
+	if reproPath == "" {
+		t.Fatalf("run() error should have a reproduction script, got: %v", runErr)
+	}
+	runner.cleanup()
+
+	reproLog := filepath.Join(t.TempDir(), "repro.log")
+	cmd := exec.Command("bash", reproPath)
+	cmd.Env = append(os.Environ(), "FAKE_BITRISE_LOG="+reproLog)
+	if out, err := cmd.CombinedOutput(); !strings.Contains(string(out), "+ bitrise run") {
+		t.Fatalf("reproduction script did not run bitrise, error = %v, output: %s", err, out)
+	}
+
+	logBytes, err := ioutil.ReadFile(reproLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(string(logBytes), "\n", 2)
+	if strings.HasPrefix(lines[0], runner.tmpDir) || filepath.Base(lines[0]) != "a" {
+		t.Errorf("reproduction should run in a new case directory, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "path::/step") || !strings.Contains(lines[1], "key: value") {
+		t.Errorf("reproduction should run the case's workflow, got config: %s", lines[1])
+	}
+	if strings.Contains(lines[1], runner.tmpDir) {
+		t.Errorf("reproduction config should not refer to the removed temporary directory, got: %s", lines[1])
+	}
+}
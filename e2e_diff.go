@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"gopkg.in/yaml.v2"
+)
+
+const previousRunConfigName = "previous.bitrise.yml"
+
+type partialStepModel struct {
+	Outputs []yaml.MapSlice `yaml:"outputs,omitempty"`
+}
+
+// e2eDiffer runs E2E workflows against the previous release of the step,
+// and compares the exit codes and exported step outputs with the run of the local step.
+type e2eDiffer struct {
+	observer      *e2eObserver
+	previousTag   string
+	outputKeys    []string
+	behaviourDiff string
+}
+
+func newE2EDiffer(commandFactory command.Factory, observer *e2eObserver, workDir string) (*e2eDiffer, error) {
+	previousTag, err := previousReleaseTag(commandFactory, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	outputKeys, err := readStepOutputKeys(filepath.Join(workDir, "step.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &e2eDiffer{
+		observer:    observer,
+		previousTag: previousTag,
+		outputKeys:  outputKeys,
+	}, nil
+}
+
+// compare executes the workflow with the previous release and records the differences from the current run.
+func (d *e2eDiffer) compare(workflow string, current e2eRunResult) {
+	var diffs []string
+	stepRef := fmt.Sprintf("git::file://%s@%s", d.observer.workDir, d.previousTag)
+	if previous, err := d.observer.observe(workflow, stepRef, previousRunConfigName); previous == nil {
+		diffs = []string{fmt.Sprintf("failed to run with %s: %s", d.previousTag, err)}
+	} else {
+		diffs = compareE2ERuns(current, *previous, d.outputKeys)
+	}
+
+	if len(diffs) > 0 {
+		d.behaviourDiff += fmt.Sprintf("- %s:\n", colorstring.Yellow(workflow))
+		for _, diff := range diffs {
+			d.behaviourDiff += fmt.Sprintf("    %s\n", diff)
+		}
+	}
+}
+
+func (d *e2eDiffer) summary() string {
+	if d.behaviourDiff == "" {
+		return "No differences found"
+	}
+	return d.behaviourDiff
+}
+
+func previousReleaseTag(commandFactory command.Factory, workDir string) (string, error) {
+	if err := fetchReleaseTags(commandFactory, workDir); err != nil {
+		return "", err
+	}
+
+	// Describing the parent commit makes sure a tagged HEAD is not compared with itself
+	cmd := commandFactory.Create("git", []string{"describe", "--tags", "--abbrev=0", "HEAD^"}, &command.Opts{Dir: workDir})
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to find previous release tag: %s: %w", out, err)
+	}
+
+	return out, nil
+}
+
+// fetchReleaseTags fetches the history and the tags of a shallow clone, which are needed to find the previous release.
+func fetchReleaseTags(commandFactory command.Factory, workDir string) error {
+	cmd := commandFactory.Create("git", []string{"rev-parse", "--is-shallow-repository"}, &command.Opts{Dir: workDir})
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to check if the repository is a shallow clone: %s: %w", out, err)
+	}
+	if out != "true" {
+		return nil
+	}
+
+	log.Printf("Fetching the history and the tags of the shallow clone")
+	cmd = commandFactory.Create("git", []string{"fetch", "--unshallow", "--tags"}, &command.Opts{Dir: workDir})
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch the tags of the shallow clone, set the clone depth of the Git Clone step to 0 (full clone) to compare with the previous release: %s: %w", out, err)
+	}
+	return nil
+}
+
+func readStepOutputKeys(stepYMLPath string) ([]string, error) {
+	stepBytes, err := ioutil.ReadFile(stepYMLPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var model partialStepModel
+	if err := yaml.Unmarshal(stepBytes, &model); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, output := range model.Outputs {
+		for _, item := range output {
+			key, ok := item.Key.(string)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast output key to string")
+			}
+			if key != "opts" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+func compareE2ERuns(current, previous e2eRunResult, outputKeys []string) []string {
+	var diffs []string
+	if current.ExitCode != previous.ExitCode {
+		diffs = append(diffs, fmt.Sprintf("exit code: %d (previous: %d)", current.ExitCode, previous.ExitCode))
+	}
+
+	keys := append([]string{}, outputKeys...)
+	sort.Strings(keys)
+	for _, key := range keys {
+		currentValue, currentOK := current.Outputs[key]
+		previousValue, previousOK := previous.Outputs[key]
+		switch {
+		case currentOK && !previousOK:
+			diffs = append(diffs, fmt.Sprintf("%s: new output %q", key, currentValue))
+		case !currentOK && previousOK:
+			diffs = append(diffs, fmt.Sprintf("%s: missing output (previous: %q)", key, previousValue))
+		case currentValue != previousValue:
+			diffs = append(diffs, fmt.Sprintf("%s: %q (previous: %q)", key, currentValue, previousValue))
+		}
+	}
+
+	return diffs
+}
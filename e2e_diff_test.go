@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_compareE2ERuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  e2eRunResult
+		previous e2eRunResult
+		want     []string
+	}{
+		{
+			"Same behaviour",
+			e2eRunResult{ExitCode: 0, Outputs: map[string]string{"OUT": "a"}},
+			e2eRunResult{ExitCode: 0, Outputs: map[string]string{"OUT": "a"}},
+			nil,
+		},
+		{
+			"Changed behaviour",
+			e2eRunResult{ExitCode: 1, Outputs: map[string]string{"NEW": "b", "OUT": "c"}},
+			e2eRunResult{ExitCode: 0, Outputs: map[string]string{"OLD": "a", "OUT": "a"}},
+			[]string{
+				`exit code: 1 (previous: 0)`,
+				`NEW: new output "b"`,
+				`OLD: missing output (previous: "a")`,
+				`OUT: "c" (previous: "a")`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareE2ERuns(tt.current, tt.previous, []string{"OUT", "OLD", "NEW"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareE2ERuns() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// durationBaselineWindow is the number of recent durations kept for each E2E test.
+	durationBaselineWindow = 10
+	// durationBaselineMinSamples is the number of recorded durations needed before regressions are detected.
+	durationBaselineMinSamples = 3
+	// defaultE2EDurationThreshold is the percent an E2E test can run longer than its median, if not set by the inputs.
+	defaultE2EDurationThreshold = 50.0
+)
+
+// durationBaseline holds the durations (in milliseconds) of the recent successful runs of each E2E test.
+// It is a plain JSON file, so it can be cached between builds.
+type durationBaseline struct {
+	Tests map[string][]int64 `json:"tests"`
+}
+
+// durationRegression is an E2E test run, which was considerably slower than the rolling median.
+type durationRegression struct {
+	Test     string
+	Duration time.Duration
+	Median   time.Duration
+}
+
+func (r durationRegression) String() string {
+	return fmt.Sprintf("%s: %s, %.0f%% slower than the median of %s", r.Test, r.Duration.Round(time.Millisecond), (float64(r.Duration)/float64(r.Median)-1)*100, r.Median.Round(time.Millisecond))
+}
+
+func readDurationBaseline(pth string) (*durationBaseline, error) {
+	baseline := &durationBaseline{Tests: map[string][]int64{}}
+
+	baselineBytes, err := ioutil.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return baseline, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(baselineBytes, baseline); err != nil {
+		return nil, fmt.Errorf("invalid duration baseline (%s): %w", pth, err)
+	}
+	if baseline.Tests == nil {
+		baseline.Tests = map[string][]int64{}
+	}
+	return baseline, nil
+}
+
+func (b *durationBaseline) write(pth string) error {
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		return err
+	}
+
+	baselineBytes, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pth, baselineBytes, 0644)
+}
+
+// check compares the duration with the median of the recorded ones. thresholdPercent is the allowed slowdown,
+// for example 50 allows a test to run 1.5 times longer than its median.
+func (b *durationBaseline) check(test string, duration time.Duration, thresholdPercent float64) *durationRegression {
+	samples := b.Tests[test]
+	if len(samples) < durationBaselineMinSamples {
+		return nil
+	}
+
+	median := time.Duration(medianOf(samples)) * time.Millisecond
+	if float64(duration) <= float64(median)*(1+thresholdPercent/100) {
+		return nil
+	}
+	return &durationRegression{Test: test, Duration: duration, Median: median}
+}
+
+func (b *durationBaseline) record(test string, duration time.Duration) {
+	samples := append(b.Tests[test], duration.Milliseconds())
+	if len(samples) > durationBaselineWindow {
+		samples = samples[len(samples)-durationBaselineWindow:]
+	}
+	b.Tests[test] = samples
+}
+
+func medianOf(values []int64) float64 {
+	sorted := append([]int64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+	return float64(sorted[middle])
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_durationBaseline_check(t *testing.T) {
+	baseline := &durationBaseline{Tests: map[string][]int64{
+		"test_a":   {1000, 1200, 1100},
+		"test_new": {1000},
+	}}
+
+	tests := []struct {
+		name     string
+		test     string
+		duration time.Duration
+		want     *durationRegression
+	}{
+		{"Within threshold", "test_a", 1600 * time.Millisecond, nil},
+		{"Regression", "test_a", 1700 * time.Millisecond, &durationRegression{Test: "test_a", Duration: 1700 * time.Millisecond, Median: 1100 * time.Millisecond}},
+		{"Not enough samples", "test_new", 10 * time.Second, nil},
+		{"Unknown test", "test_b", 10 * time.Second, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := baseline.check(tt.test, tt.duration, 50); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_durationBaseline_record(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "cache", "durations.json")
+	baseline, err := readDurationBaseline(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= durationBaselineWindow+2; i++ {
+		baseline.record("test_a", time.Duration(i)*time.Second)
+	}
+	if err := baseline.write(pth); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readDurationBaseline(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := got.Tests["test_a"]
+	if len(samples) != durationBaselineWindow || samples[0] != 3000 || samples[len(samples)-1] != 12000 {
+		t.Errorf("record() got samples = %v, want the last %d durations", samples, durationBaselineWindow)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	failFastPolicy       = "fail_fast"
+	runAllPolicy         = "run_all"
+	maxFailuresPolicyKey = "max_failures"
+)
+
+// e2eFailurePolicy decides when to stop running the remaining E2E tests.
+type e2eFailurePolicy struct {
+	// MaxFailures is the number of failed tests after which the run stops, 0 means all tests are run.
+	MaxFailures int
+	// Reason describes why the policy is in effect.
+	Reason string
+}
+
+// parseE2EFailurePolicy parses the e2e_failure_policy input. If it is not set, the policy is inferred:
+// local and PR builds fail fast, while other CI builds (like scheduled ones) run all tests.
+func parseE2EFailurePolicy(value string, isCI, isPR bool) (e2eFailurePolicy, error) {
+	switch {
+	case value == "":
+		if !isCI {
+			return e2eFailurePolicy{MaxFailures: 1, Reason: "inferred, not running on CI"}, nil
+		}
+		if isPR {
+			return e2eFailurePolicy{MaxFailures: 1, Reason: "inferred, running for a pull request"}, nil
+		}
+		return e2eFailurePolicy{MaxFailures: 0, Reason: "inferred, running on CI, not for a pull request"}, nil
+	case value == failFastPolicy:
+		return e2eFailurePolicy{MaxFailures: 1, Reason: "set by e2e_failure_policy input"}, nil
+	case value == runAllPolicy:
+		return e2eFailurePolicy{MaxFailures: 0, Reason: "set by e2e_failure_policy input"}, nil
+	case strings.HasPrefix(value, maxFailuresPolicyKey+"="):
+		maxFailures, err := strconv.Atoi(strings.TrimPrefix(value, maxFailuresPolicyKey+"="))
+		if err != nil || maxFailures < 1 {
+			return e2eFailurePolicy{}, fmt.Errorf("invalid E2E failure policy (%s): %s=N requires a positive integer", value, maxFailuresPolicyKey)
+		}
+		return e2eFailurePolicy{MaxFailures: maxFailures, Reason: "set by e2e_failure_policy input"}, nil
+	default:
+		return e2eFailurePolicy{}, fmt.Errorf("invalid E2E failure policy (%s): available policies: %s, %s, %s=N", value, failFastPolicy, runAllPolicy, maxFailuresPolicyKey)
+	}
+}
+
+func (p e2eFailurePolicy) shouldStop(failures int) bool {
+	return p.MaxFailures > 0 && failures >= p.MaxFailures
+}
+
+func (p e2eFailurePolicy) String() string {
+	switch p.MaxFailures {
+	case 0:
+		return runAllPolicy
+	case 1:
+		return failFastPolicy
+	default:
+		return fmt.Sprintf("%s=%d", maxFailuresPolicyKey, p.MaxFailures)
+	}
+}
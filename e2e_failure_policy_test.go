@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_parseE2EFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		isCI    bool
+		isPR    bool
+		want    string
+		wantErr bool
+	}{
+		{"Inferred for local runs", "", false, false, failFastPolicy, false},
+		{"Inferred for PRs", "", true, true, failFastPolicy, false},
+		{"Inferred for other CI runs", "", true, false, runAllPolicy, false},
+		{"Explicit fail fast on CI", "fail_fast", true, false, failFastPolicy, false},
+		{"Explicit run all locally", "run_all", false, false, runAllPolicy, false},
+		{"Max failures", "max_failures=3", true, true, "max_failures=3", false},
+		{"Max failures of 1", "max_failures=1", true, true, failFastPolicy, false},
+		{"Invalid max failures", "max_failures=0", false, false, "", true},
+		{"Unknown policy", "retry", false, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseE2EFailurePolicy(tt.value, tt.isCI, tt.isPR)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseE2EFailurePolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("parseE2EFailurePolicy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_e2eFailurePolicy_shouldStop(t *testing.T) {
+	runAll := e2eFailurePolicy{MaxFailures: 0}
+	if runAll.shouldStop(10) {
+		t.Errorf("shouldStop() run_all policy should never stop")
+	}
+
+	maxFailures := e2eFailurePolicy{MaxFailures: 2}
+	if maxFailures.shouldStop(1) || !maxFailures.shouldStop(2) {
+		t.Errorf("shouldStop() max_failures=2 policy should stop at the second failure")
+	}
+}
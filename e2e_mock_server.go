@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	mockServerRoutesFileName = "mock_server.yml"
+	mockServerURLEnvKey      = "E2E_MOCK_SERVER_URL"
+)
+
+// mockServerModel is the route configuration of the E2E mock HTTP server, stored in e2e/mock_server.yml:
+//
+//	routes:
+//	- method: POST
+//	  path: /api/upload
+//	  status: 201
+//	  headers:
+//	    Content-Type: application/json
+//	  body: '{"id": "1"}'
+//	  tests: [test_upload]
+//	  expect_calls: 1
+//	  expect_body:
+//	    matches: '"name": ?"app.ipa"'
+//	  expect_headers:
+//	    Authorization:
+//	      equals: token secret
+//
+// Every request is recorded with the E2E test sending it. The expectations of a route apply to each test
+// listed in tests (workflow IDs and case_<name> for test cases), or to the whole run if no test is listed.
+// expect_body and expect_headers are checked against every matching request.
+type mockServerModel struct {
+	Routes []mockRoute `yaml:"routes"`
+}
+
+type mockRoute struct {
+	Method      string            `yaml:"method"`
+	Path        string            `yaml:"path"`
+	Status      int               `yaml:"status"`
+	Headers     map[string]string `yaml:"headers"`
+	Body        string            `yaml:"body"`
+	Tests       []string          `yaml:"tests"`
+	ExpectCalls *int              `yaml:"expect_calls"`
+	ExpectBody  *e2eValueMatcher  `yaml:"expect_body"`
+	// ExpectHeaders are keyed by the header names, the header values are matched.
+	ExpectHeaders map[string]e2eValueMatcher `yaml:"expect_headers"`
+}
+
+// mockRequest is a request received by the mock server.
+type mockRequest struct {
+	// Test is the E2E test running when the request was received.
+	Test    string
+	Method  string
+	Path    string
+	Body    string
+	Headers http.Header
+}
+
+func (r mockRequest) String() string {
+	return r.Method + " " + r.Path
+}
+
+func (r mockRoute) String() string {
+	return r.Method + " " + r.Path
+}
+
+// mockServer is a local HTTP stub server, serving the configured routes and recording the received requests.
+type mockServer struct {
+	routes []mockRoute
+	server *http.Server
+	url    string
+
+	mu       sync.Mutex
+	requests []mockRequest
+	// test is the E2E test sending the requests, ran are all the tests started.
+	test string
+	ran  map[string]bool
+	// ignoring is set while the requests are served without being recorded.
+	ignoring bool
+}
+
+func readMockRoutesFromBytes(routesBytes []byte) ([]mockRoute, error) {
+	var model mockServerModel
+	if err := yaml.UnmarshalStrict(routesBytes, &model); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i, route := range model.Routes {
+		if route.Path == "" || !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("route %d: path must start with /", i)
+		}
+		if route.Method == "" {
+			route.Method = http.MethodGet
+		}
+		route.Method = strings.ToUpper(route.Method)
+		if route.Status == 0 {
+			route.Status = http.StatusOK
+		}
+		if seen[route.String()] {
+			return nil, fmt.Errorf("duplicate route: %s", route)
+		}
+		seen[route.String()] = true
+
+		if route.ExpectBody != nil {
+			if err := compileMockMatcher(route.ExpectBody); err != nil {
+				return nil, fmt.Errorf("route %s: body: %w", route, err)
+			}
+		}
+		for key, matcher := range route.ExpectHeaders {
+			if err := compileMockMatcher(&matcher); err != nil {
+				return nil, fmt.Errorf("route %s: header %s: %w", route, key, err)
+			}
+			route.ExpectHeaders[key] = matcher
+		}
+
+		model.Routes[i] = route
+	}
+
+	return model.Routes, nil
+}
+
+func compileMockMatcher(matcher *e2eValueMatcher) error {
+	if matcher.Equals == nil && matcher.Matches == "" {
+		return fmt.Errorf("no expectation set")
+	}
+	return matcher.compile()
+}
+
+func startMockServer(routesPath string) (*mockServer, error) {
+	routesBytes, err := ioutil.ReadFile(routesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := readMockRoutesFromBytes(routesBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mock server routes (%s): %w", routesPath, err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &mockServer{
+		routes: routes,
+		url:    "http://" + listener.Addr().String(),
+		ran:    map[string]bool{},
+	}
+	s.server = &http.Server{Handler: s}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warnf("Mock server stopped: %s", err)
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *mockServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Warnf("Mock server failed to read request body: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request := mockRequest{
+		Test:    s.test,
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Body:    string(body),
+		Headers: req.Header.Clone(),
+	}
+	if !s.ignoring {
+		s.requests = append(s.requests, request)
+	}
+
+	route := s.route(request)
+	if route == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	for key, value := range route.Headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(route.Status)
+	if _, err := w.Write([]byte(route.Body)); err != nil {
+		log.Warnf("Mock server failed to write response: %s", err)
+	}
+}
+
+// startTest records the following requests as sent by the given E2E test. It does nothing if there is no mock server.
+func (s *mockServer) startTest(test string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.test = test
+	s.ran[test] = true
+}
+
+// withoutRecording serves the requests sent by fn without recording them, so they are not verified.
+// Runs of the previous step release use it, as only the calls of the current step are expected.
+// fn is simply called if there is no mock server.
+func (s *mockServer) withoutRecording(fn func()) {
+	if s == nil {
+		fn()
+		return
+	}
+
+	s.setIgnoring(true)
+	defer s.setIgnoring(false)
+	fn()
+}
+
+func (s *mockServer) setIgnoring(ignoring bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignoring = ignoring
+}
+
+func (s *mockServer) stop() {
+	if err := s.server.Close(); err != nil {
+		log.Warnf("Failed to stop mock server: %s", err)
+	}
+}
+
+// verify checks the recorded requests against the expectations of the routes.
+func (s *mockServer) verify() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failures []string
+	for _, route := range s.routes {
+		if len(route.Tests) == 0 {
+			failures = append(failures, s.verifyRoute(route, "", func(mockRequest) bool { return true })...)
+			continue
+		}
+		for _, test := range route.Tests {
+			if !s.ran[test] {
+				// Not selected for this run
+				continue
+			}
+			test := test
+			failures = append(failures, s.verifyRoute(route, test, func(req mockRequest) bool { return req.Test == test })...)
+		}
+	}
+
+	for _, req := range s.requests {
+		if s.route(req) == nil {
+			failures = append(failures, fmt.Sprintf("%s: unexpected request%s", req, mockScope(req.Test)))
+		}
+	}
+
+	return failures
+}
+
+// verifyRoute checks the requests of the route, which are selected by inScope.
+func (s *mockServer) verifyRoute(route mockRoute, test string, inScope func(mockRequest) bool) []string {
+	var failures []string
+	calls := 0
+	for _, req := range s.requests {
+		if req.Method != route.Method || req.Path != route.Path || !inScope(req) {
+			continue
+		}
+		calls++
+
+		if route.ExpectBody != nil {
+			if err := route.ExpectBody.match(req.Body); err != nil {
+				failures = append(failures, fmt.Sprintf("%s%s: request %d: body: %s", route, mockScope(test), calls, err))
+			}
+		}
+		for _, key := range sortedMatcherKeys(route.ExpectHeaders) {
+			values, ok := req.Headers[http.CanonicalHeaderKey(key)]
+			if !ok {
+				failures = append(failures, fmt.Sprintf("%s%s: request %d: header %s: not sent", route, mockScope(test), calls, key))
+				continue
+			}
+			if err := route.ExpectHeaders[key].match(strings.Join(values, ", ")); err != nil {
+				failures = append(failures, fmt.Sprintf("%s%s: request %d: header %s: %s", route, mockScope(test), calls, key, err))
+			}
+		}
+	}
+
+	if route.ExpectCalls != nil && calls != *route.ExpectCalls {
+		failures = append(failures, fmt.Sprintf("%s%s: expected %d calls, got %d", route, mockScope(test), *route.ExpectCalls, calls))
+	}
+	return failures
+}
+
+func (s *mockServer) route(req mockRequest) *mockRoute {
+	for i, route := range s.routes {
+		if route.Method == req.Method && route.Path == req.Path {
+			return &s.routes[i]
+		}
+	}
+	return nil
+}
+
+func mockScope(test string) string {
+	if test == "" {
+		return ""
+	}
+	return " (" + test + ")"
+}
+
+func sortedMatcherKeys(m map[string]e2eValueMatcher) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readMockRoutesFromBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		routesBytes []byte
+		want        []string
+		wantErr     bool
+	}{
+		{
+			"Defaults applied",
+			[]byte(`routes:
+- path: /status
+- method: post
+  path: /upload
+  status: 201
+`),
+			[]string{"GET /status", "POST /upload"},
+			false,
+		},
+		{
+			"Duplicate route",
+			[]byte(`routes:
+- path: /status
+- method: GET
+  path: /status
+`),
+			nil,
+			true,
+		},
+		{
+			"Header expectation without matcher",
+			[]byte(`routes:
+- path: /status
+  expect_headers:
+    Authorization: {}
+`),
+			nil,
+			true,
+		},
+		{
+			"Invalid body pattern",
+			[]byte(`routes:
+- path: /status
+  expect_body:
+    matches: '[a-'
+`),
+			nil,
+			true,
+		},
+		{
+			"Invalid path",
+			[]byte(`routes:
+- path: status
+`),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMockRoutesFromBytes(tt.routesBytes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readMockRoutesFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var routes []string
+			for _, route := range got {
+				routes = append(routes, route.String())
+			}
+			if !reflect.DeepEqual(routes, tt.want) {
+				t.Errorf("readMockRoutesFromBytes() got = %v, want %v", routes, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mockServer(t *testing.T) {
+	routesPath := filepath.Join(t.TempDir(), mockServerRoutesFileName)
+	if err := ioutil.WriteFile(routesPath, []byte(`routes:
+- path: /status
+  body: ok
+  expect_calls: 2
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mock, err := startMockServer(routesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.stop()
+
+	resp, err := http.Get(mock.url + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err != nil || string(body) != "ok" {
+		t.Errorf("GET /status got = %s, %v", body, err)
+	}
+
+	resp, err = http.Get(mock.url + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /unknown got status = %d", resp.StatusCode)
+	}
+
+	// Requests of the previous release's run are not recorded
+	mock.withoutRecording(func() {
+		for _, pth := range []string{"/status", "/unknown"} {
+			resp, err := http.Get(mock.url + pth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := resp.Body.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	want := []string{
+		"GET /status: expected 2 calls, got 1",
+		"GET /unknown: unexpected request",
+	}
+	if got := mock.verify(); !reflect.DeepEqual(got, want) {
+		t.Errorf("verify() got = %v, want %v", got, want)
+	}
+}
+
+func Test_mockServer_requestExpectations(t *testing.T) {
+	routesPath := filepath.Join(t.TempDir(), mockServerRoutesFileName)
+	if err := ioutil.WriteFile(routesPath, []byte(`routes:
+- method: POST
+  path: /upload
+  tests: [test_upload, case_upload, test_not_selected]
+  expect_calls: 1
+  expect_body:
+    matches: ^name=app\.ipa$
+  expect_headers:
+    Authorization:
+      equals: token secret
+- path: /status
+  expect_calls: 2
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mock, err := startMockServer(routesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.stop()
+
+	send := func(method, pth, body, token string) {
+		req, err := http.NewRequest(method, mock.url+pth, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mock.startTest("test_upload")
+	send(http.MethodPost, "/upload", "name=app.ipa", "token secret")
+	send(http.MethodGet, "/status", "", "")
+
+	mock.startTest("case_upload")
+	send(http.MethodPost, "/upload", "name=app.apk", "")
+	send(http.MethodPost, "/upload", "name=app.ipa", "token secret")
+	send(http.MethodGet, "/status", "", "")
+
+	// Calls of tests the route is not scoped to are served, but not verified
+	mock.startTest("test_other")
+	send(http.MethodPost, "/upload", "", "")
+	send(http.MethodGet, "/unknown", "", "")
+
+	want := []string{
+		`POST /upload (case_upload): request 1: body: expected to match "^name=app\\.ipa$", got "name=app.apk"`,
+		"POST /upload (case_upload): request 1: header Authorization: not sent",
+		"POST /upload (case_upload): expected 1 calls, got 2",
+		"GET /unknown: unexpected request (test_other)",
+	}
+	if got := mock.verify(); !reflect.DeepEqual(got, want) {
+		t.Errorf("verify() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	localStepReferencePrefix = "path::"
+	dumpOutputsWorkflow      = "_steps_check_dump_outputs"
+	currentRunConfigName     = "current.bitrise.yml"
+)
+
+// e2eRunResult is the observable behaviour of a single E2E workflow run.
+type e2eRunResult struct {
+	ExitCode int
+	Outputs  map[string]string
+	Usage    e2eResourceUsage
+}
+
+// e2eObserver runs E2E workflows from a rewritten copy of the E2E bitrise.yml,
+// to collect the exit code and the exported step outputs of the run.
+type e2eObserver struct {
+	workDir     string
+	configBytes []byte
+	secretsPath string
+	outputKeys  []string
+	tmpDir      string
+	// timeout is the maximum duration of a run, no timeout is applied if 0.
+	timeout time.Duration
+}
+
+func newE2EObserver(workDir, configPath, secretsPath string, outputKeys []string) (*e2eObserver, error) {
+	configBytes, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "e2e-observer")
+	if err != nil {
+		return nil, err
+	}
+
+	return &e2eObserver{
+		workDir:     workDir,
+		configBytes: configBytes,
+		secretsPath: secretsPath,
+		outputKeys:  outputKeys,
+		tmpDir:      tmpDir,
+	}, nil
+}
+
+func (o *e2eObserver) cleanup() {
+	if err := os.RemoveAll(o.tmpDir); err != nil {
+		log.Warnf("Failed to remove temporary directory (%s): %s", o.tmpDir, err)
+	}
+}
+
+// observe runs the workflow with every local step reference pointing to stepRef.
+// The result is nil if the workflow could not be run at all.
+func (o *e2eObserver) observe(workflow, stepRef, configName string) (*e2eRunResult, error) {
+	dumpPath := filepath.Join(o.tmpDir, workflow+".env")
+	if err := os.RemoveAll(dumpPath); err != nil {
+		return nil, err
+	}
+
+	configBytes, err := rewriteE2EConfig(o.configBytes, o.workDir, workflow, stepRef, dumpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := filepath.Join(o.tmpDir, configName)
+	if err := ioutil.WriteFile(configPath, configBytes, 0600); err != nil {
+		return nil, err
+	}
+
+	result := e2eRunResult{}
+	var runErr error
+	result.Usage, runErr = runE2EWorkflow(o.workDir, configPath, o.secretsPath, workflow, o.timeout)
+	if runErr != nil {
+		// The dump path is removed with the temporary directory, the reproduction runs the workflow without dumping
+		if reproConfigBytes, err := rewriteE2EConfig(o.configBytes, o.workDir, workflow, stepRef, ""); err == nil {
+			updateRepro(runErr, func(script *reproScript) { script.replaceFile(configPath, string(reproConfigBytes)) })
+		}
+
+		exitCode, ok := exitCodeOf(runErr)
+		if !ok {
+			return nil, runErr
+		}
+		result.ExitCode = exitCode
+	}
+
+	dumpBytes, err := ioutil.ReadFile(dumpPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	result.Outputs = parseEnvDump(dumpBytes, o.outputKeys)
+
+	return &result, runErr
+}
+
+// rewriteE2EConfig points every reference to the step in workDir to stepRef, and makes the given workflow
+// dump its environment into dumpPath once it finished (even if it failed). Nothing is dumped if dumpPath is empty.
+func rewriteE2EConfig(configBytes []byte, workDir, workflow, stepRef, dumpPath string) ([]byte, error) {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(configBytes, &config); err != nil {
+		return nil, err
+	}
+
+	workflowsIdx := mapSliceIndex(config, "workflows")
+	if workflowsIdx == -1 {
+		return nil, fmt.Errorf("no workflows found in E2E config")
+	}
+	workflows, ok := config[workflowsIdx].Value.(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("invalid workflows in E2E config")
+	}
+
+	found := false
+	for i, item := range workflows {
+		model, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		replaceLocalStepReference(model, workDir, stepRef)
+
+		if item.Key == workflow {
+			found = true
+			if dumpPath == "" {
+				continue
+			}
+			afterRun := []interface{}{}
+			if idx := mapSliceIndex(model, "after_run"); idx != -1 {
+				afterRun, _ = model[idx].Value.([]interface{})
+				model = append(model[:idx], model[idx+1:]...)
+			}
+			model = append(model, yaml.MapItem{Key: "after_run", Value: append(afterRun, dumpOutputsWorkflow)})
+			workflows[i].Value = model
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("workflow %s not found in E2E config", workflow)
+	}
+
+	if dumpPath != "" {
+		workflows = append(workflows, yaml.MapItem{Key: dumpOutputsWorkflow, Value: yaml.MapSlice{
+			{Key: "steps", Value: []interface{}{dumpEnvStep(dumpPath)}},
+		}})
+	}
+	config[workflowsIdx].Value = workflows
+
+	return yaml.Marshal(config)
+}
+
+// dumpEnvStep returns a step, which writes the environment (including the exported step outputs) into dumpPath,
+// even if a previous step failed.
+func dumpEnvStep(dumpPath string) yaml.MapSlice {
+	return yaml.MapSlice{{Key: "script@1", Value: yaml.MapSlice{
+		{Key: "title", Value: "Dump step outputs"},
+		{Key: "is_always_run", Value: true},
+		{Key: "inputs", Value: []interface{}{
+			yaml.MapSlice{{Key: "content", Value: "#!/usr/bin/env bash\nenv -0 > " + shellQuote(dumpPath)}},
+		}},
+	}}}
+}
+
+func replaceLocalStepReference(workflow yaml.MapSlice, workDir, stepRef string) {
+	idx := mapSliceIndex(workflow, "steps")
+	if idx == -1 {
+		return
+	}
+
+	steps, ok := workflow[idx].Value.([]interface{})
+	if !ok {
+		return
+	}
+	for _, step := range steps {
+		stepItem, ok := step.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		for j := range stepItem {
+			if key, ok := stepItem[j].Key.(string); ok && isLocalStepReference(key, workDir) {
+				stepItem[j].Key = stepRef
+			}
+		}
+	}
+}
+
+// isLocalStepReference tells if the step reference points to the step in workDir,
+// like path::./, path::. or the absolute path of workDir. Relative paths are relative to workDir,
+// where the E2E workflows run.
+func isLocalStepReference(ref, workDir string) bool {
+	if !strings.HasPrefix(ref, localStepReferencePrefix) {
+		return false
+	}
+
+	pth := strings.TrimPrefix(ref, localStepReferencePrefix)
+	if pth == "" {
+		return false
+	}
+	if !filepath.IsAbs(pth) {
+		pth = filepath.Join(workDir, pth)
+	}
+	return filepath.Clean(pth) == filepath.Clean(workDir)
+}
+
+func mapSliceIndex(slice yaml.MapSlice, key string) int {
+	for i, item := range slice {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseEnvDump parses the output of `env -0`, keeping only the given keys.
+func parseEnvDump(dump []byte, keys []string) map[string]string {
+	envs := map[string]string{}
+	for _, entry := range bytes.Split(dump, []byte{0}) {
+		split := strings.SplitN(string(entry), "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		for _, key := range keys {
+			if split[0] == key {
+				envs[key] = split[1]
+			}
+		}
+	}
+	return envs
+}
@@ -0,0 +1,120 @@
+package main
+
+import "testing"
+
+func Test_rewriteE2EConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		configBytes []byte
+		workflow    string
+		dumpPath    string
+		want        string
+		wantErr     bool
+	}{
+		{
+			"Local step reference replaced and outputs dumped",
+			[]byte(`format_version: "11"
+workflows:
+  test_a:
+    after_run:
+    - _run
+  _run:
+    steps:
+    - script: {}
+    - path::./:
+        inputs:
+        - verbose: true
+    - path::/bitrise/src/: {}
+    - path::./e2e/other-step: {}
+`),
+			"test_a",
+			"/tmp/test_a.env",
+			`format_version: "11"
+workflows:
+  test_a:
+    after_run:
+    - _run
+    - _steps_check_dump_outputs
+  _run:
+    steps:
+    - script: {}
+    - git::file:///step@1.0.0:
+        inputs:
+        - verbose: true
+    - git::file:///step@1.0.0: {}
+    - path::./e2e/other-step: {}
+  _steps_check_dump_outputs:
+    steps:
+    - script@1:
+        title: Dump step outputs
+        is_always_run: true
+        inputs:
+        - content: |-
+            #!/usr/bin/env bash
+            env -0 > '/tmp/test_a.env'
+`,
+			false,
+		},
+		{
+			"Nothing dumped without dump path",
+			[]byte(`workflows:
+  test_a:
+    steps:
+    - path::./: {}
+`),
+			"test_a",
+			"",
+			`workflows:
+  test_a:
+    steps:
+    - git::file:///step@1.0.0: {}
+`,
+			false,
+		},
+		{
+			"Unknown workflow",
+			[]byte(`workflows:
+  test_a: {}
+`),
+			"test_b",
+			"/tmp/test_a.env",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rewriteE2EConfig(tt.configBytes, "/bitrise/src", tt.workflow, "git::file:///step@1.0.0", tt.dumpPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rewriteE2EConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("rewriteE2EConfig() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isLocalStepReference(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"path::./", true},
+		{"path::.", true},
+		{"path::/bitrise/src", true},
+		{"path::/bitrise/src/", true},
+		{"path::./e2e/..", true},
+		{"path::./e2e/other-step", false},
+		{"path::", false},
+		{"script@1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := isLocalStepReference(tt.ref, "/bitrise/src"); got != tt.want {
+				t.Errorf("isLocalStepReference() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
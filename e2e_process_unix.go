@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group, so that its child processes can be killed with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the started command's process group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// forwardSignals forwards SIGINT and SIGTERM to the started command's process group, which is not the terminal's
+// foreground group, so it does not receive the signals of the terminal. The returned function stops forwarding,
+// and returns the last forwarded signal (nil if there was none).
+func forwardSignals(cmd *exec.Cmd) func() os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan os.Signal)
+	go func() {
+		var forwarded os.Signal
+		for {
+			select {
+			case sig := <-signals:
+				if err := syscall.Kill(-cmd.Process.Pid, sig.(syscall.Signal)); err == nil {
+					forwarded = sig
+				}
+			case done <- forwarded:
+				return
+			}
+		}
+	}()
+
+	return func() os.Signal {
+		signal.Stop(signals)
+		return <-done
+	}
+}
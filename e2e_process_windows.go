@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+// killProcessGroup kills the started command with its child processes by taskkill. If taskkill fails, only the
+// command is killed, its child processes are left running.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// forwardSignals does nothing on Windows, the command is not started in a new process group.
+func forwardSignals(*exec.Cmd) func() os.Signal {
+	return func() os.Signal { return nil }
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	quarantineFileName   = "quarantine.yml"
+	quarantineDateLayout = "2006-01-02"
+)
+
+// quarantineModel lists the E2E tests which currently fail for a known reason, stored in e2e/quarantine.yml:
+//
+//	quarantine:
+//	- workflow: test_flaky_upload
+//	  owner: jane.doe
+//	  reason: The upload service times out on the Xcode 16 stack
+//	  expires: "2026-11-30"
+//
+// Quarantined tests still run, but their failure does not fail the build until the entry expires.
+type quarantineModel struct {
+	Quarantine []quarantineEntry `yaml:"quarantine"`
+}
+
+type quarantineEntry struct {
+	Workflow string `yaml:"workflow"`
+	Owner    string `yaml:"owner"`
+	Reason   string `yaml:"reason"`
+	Expires  string `yaml:"expires"`
+}
+
+// e2eQuarantine holds the active (not expired) quarantine entries by test name.
+type e2eQuarantine struct {
+	active  map[string]quarantineEntry
+	expired []quarantineEntry
+}
+
+func readQuarantine(quarantinePath string, now time.Time) (*e2eQuarantine, error) {
+	quarantineBytes, err := ioutil.ReadFile(quarantinePath)
+	if os.IsNotExist(err) {
+		return &e2eQuarantine{active: map[string]quarantineEntry{}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	quarantine, err := readQuarantineFromBytes(quarantineBytes, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quarantine file (%s): %w", quarantinePath, err)
+	}
+	return quarantine, nil
+}
+
+func readQuarantineFromBytes(quarantineBytes []byte, now time.Time) (*e2eQuarantine, error) {
+	var model quarantineModel
+	if err := yaml.UnmarshalStrict(quarantineBytes, &model); err != nil {
+		return nil, err
+	}
+
+	quarantine := &e2eQuarantine{active: map[string]quarantineEntry{}}
+	seen := map[string]bool{}
+	for _, entry := range model.Quarantine {
+		if entry.Workflow == "" || entry.Owner == "" || entry.Reason == "" || entry.Expires == "" {
+			return nil, fmt.Errorf("entry %s: workflow, owner, reason and expires are required", entry.Workflow)
+		}
+		if seen[entry.Workflow] {
+			return nil, fmt.Errorf("duplicate entry: %s", entry.Workflow)
+		}
+		seen[entry.Workflow] = true
+
+		expires, err := time.Parse(quarantineDateLayout, entry.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: invalid expiry date, expected YYYY-MM-DD: %w", entry.Workflow, err)
+		}
+
+		// The entry is still valid on the day of its expiry date
+		if now.After(expires.AddDate(0, 0, 1)) {
+			quarantine.expired = append(quarantine.expired, entry)
+			continue
+		}
+		quarantine.active[entry.Workflow] = entry
+	}
+
+	return quarantine, nil
+}
+
+func (q *e2eQuarantine) entry(test string) (quarantineEntry, bool) {
+	entry, ok := q.active[test]
+	return entry, ok
+}
+
+func (e quarantineEntry) String() string {
+	return fmt.Sprintf("owner: %s, expires: %s, reason: %s", e.Owner, e.Expires, e.Reason)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_readQuarantineFromBytes(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		bytes       []byte
+		wantActive  []string
+		wantExpired []string
+		wantErr     bool
+	}{
+		{
+			"Active and expired entries",
+			[]byte(`quarantine:
+- workflow: test_active
+  owner: jane.doe
+  reason: Flaky upload
+  expires: "2026-11-30"
+- workflow: test_expires_today
+  owner: jane.doe
+  reason: Flaky upload
+  expires: "2026-10-19"
+- workflow: test_expired
+  owner: john.doe
+  reason: Broken stack
+  expires: "2026-10-18"
+`),
+			[]string{"test_active", "test_expires_today"},
+			[]string{"test_expired"},
+			false,
+		},
+		{
+			"Missing reason",
+			[]byte(`quarantine:
+- workflow: test_active
+  owner: jane.doe
+  expires: "2026-11-30"
+`),
+			nil,
+			nil,
+			true,
+		},
+		{
+			"Invalid date",
+			[]byte(`quarantine:
+- workflow: test_active
+  owner: jane.doe
+  reason: Flaky upload
+  expires: 30/11/2026
+`),
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readQuarantineFromBytes(tt.bytes, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readQuarantineFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if len(got.active) != len(tt.wantActive) {
+				t.Errorf("readQuarantineFromBytes() got %d active entries, want %d", len(got.active), len(tt.wantActive))
+			}
+			for _, workflow := range tt.wantActive {
+				if _, ok := got.entry(workflow); !ok {
+					t.Errorf("readQuarantineFromBytes() %s is not quarantined", workflow)
+				}
+			}
+			var expired []string
+			for _, entry := range got.expired {
+				expired = append(expired, entry.Workflow)
+			}
+			if !reflect.DeepEqual(expired, tt.wantExpired) {
+				t.Errorf("readQuarantineFromBytes() got expired = %v, want %v", expired, tt.wantExpired)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "path"
+
+// selectE2ETests returns the tests matching any of the include patterns (all of them if there are none) and none of
+// the exclude patterns, in their original order, and the names of the other tests.
+func selectE2ETests(tests []e2eTest, include, exclude []string) ([]e2eTest, []string) {
+	var selected []e2eTest
+	var deselected []string
+	for _, test := range tests {
+		if matchesAnyE2EPattern(test.Name, include, true) && !matchesAnyE2EPattern(test.Name, exclude, false) {
+			selected = append(selected, test)
+		} else {
+			deselected = append(deselected, test.Name)
+		}
+	}
+	return selected, deselected
+}
+
+// matchesAnyE2EPattern matches the test name against the glob patterns, returning ifEmpty if there are no patterns.
+func matchesAnyE2EPattern(name string, patterns []string, ifEmpty bool) bool {
+	if len(patterns) == 0 {
+		return ifEmpty
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_selectE2ETests(t *testing.T) {
+	tests := []e2eTest{{Name: "test_simple"}, {Name: "test_slow_upload"}, {Name: "utility_setup"}, {Name: "test_cache"}}
+
+	cases := []struct {
+		name           string
+		include        []string
+		exclude        []string
+		wantSelected   []string
+		wantDeselected []string
+	}{
+		{
+			name:         "No patterns",
+			wantSelected: []string{"test_simple", "test_slow_upload", "utility_setup", "test_cache"},
+		},
+		{
+			name:           "Include",
+			include:        []string{"test_*"},
+			wantSelected:   []string{"test_simple", "test_slow_upload", "test_cache"},
+			wantDeselected: []string{"utility_setup"},
+		},
+		{
+			name:           "Include and exclude",
+			include:        []string{"test_*"},
+			exclude:        []string{"test_slow_*", "test_cache"},
+			wantSelected:   []string{"test_simple"},
+			wantDeselected: []string{"test_slow_upload", "utility_setup", "test_cache"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, deselected := selectE2ETests(tests, tt.include, tt.exclude)
+			var selectedNames []string
+			for _, test := range selected {
+				selectedNames = append(selectedNames, test.Name)
+			}
+			if !reflect.DeepEqual(selectedNames, tt.wantSelected) {
+				t.Errorf("selectE2ETests() selected = %v, want %v", selectedNames, tt.wantSelected)
+			}
+			if !reflect.DeepEqual(deselected, tt.wantDeselected) {
+				t.Errorf("selectE2ETests() deselected = %v, want %v", deselected, tt.wantDeselected)
+			}
+		})
+	}
+}
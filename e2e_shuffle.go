@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// e2eShuffleSeed returns the seed for shuffling the E2E test order: the configured one if set, a random one otherwise.
+func e2eShuffleSeed(configured string) (int64, error) {
+	if configured == "" {
+		return time.Now().UnixNano(), nil
+	}
+
+	seed, err := strconv.ParseInt(configured, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid E2E shuffle seed (%s): %w", configured, err)
+	}
+	return seed, nil
+}
+
+// shuffleE2ETests shuffles the tests in place, the same seed always results in the same order.
+func shuffleE2ETests(tests []e2eTest, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(tests), func(i, j int) {
+		tests[i], tests[j] = tests[j], tests[i]
+	})
+}
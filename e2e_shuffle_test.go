@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_shuffleE2ETests(t *testing.T) {
+	names := func(tests []e2eTest) []string {
+		var names []string
+		for _, test := range tests {
+			names = append(names, test.Name)
+		}
+		return names
+	}
+	newTests := func() []e2eTest {
+		var tests []e2eTest
+		for _, name := range []string{"test_a", "test_b", "test_c", "test_d", "test_e", "test_f"} {
+			tests = append(tests, e2eTest{Name: name})
+		}
+		return tests
+	}
+
+	first, second := newTests(), newTests()
+	shuffleE2ETests(first, 42)
+	shuffleE2ETests(second, 42)
+	if !reflect.DeepEqual(names(first), names(second)) {
+		t.Errorf("shuffleE2ETests() same seed resulted in different orders: %v, %v", names(first), names(second))
+	}
+	if reflect.DeepEqual(names(first), names(newTests())) {
+		t.Errorf("shuffleE2ETests() did not change the order")
+	}
+}
+
+func Test_e2eShuffleSeed(t *testing.T) {
+	if seed, err := e2eShuffleSeed("1234"); err != nil || seed != 1234 {
+		t.Errorf("e2eShuffleSeed() got = %d, %v, want 1234", seed, err)
+	}
+	if _, err := e2eShuffleSeed("random"); err == nil {
+		t.Errorf("e2eShuffleSeed() expected error for invalid seed")
+	}
+	if _, err := e2eShuffleSeed(""); err != nil {
+		t.Errorf("e2eShuffleSeed() error = %v", err)
+	}
+}
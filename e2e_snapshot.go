@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	snapshotsDirName        = "snapshots"
+	outputsSnapshotFileName = "outputs.json"
+	filesSnapshotDirName    = "files"
+)
+
+var errSnapshotMismatch = errors.New("snapshot mismatch")
+
+// e2eSnapshotConfig lists the step outputs and files to be compared with golden files after an E2E workflow run.
+// Workflows opt into snapshotting in their meta section:
+//
+//	test_export:
+//	  meta:
+//	    steps-check:
+//	      snapshot:
+//	        outputs:
+//	        - EXPORTED_PATH
+//	        files:
+//	        - _tmp/result.json
+//
+// If no outputs are listed, all outputs of step.yml are snapshotted. Files are relative to the step directory.
+type e2eSnapshotConfig struct {
+	Outputs []string `yaml:"outputs"`
+	Files   []string `yaml:"files"`
+}
+
+type partialE2EWorkflowMetaModel struct {
+	Workflows map[string]struct {
+		Meta struct {
+			StepsCheck struct {
+				Snapshot *e2eSnapshotConfig `yaml:"snapshot"`
+			} `yaml:"steps-check"`
+		} `yaml:"meta"`
+	} `yaml:"workflows"`
+}
+
+func readE2ESnapshotConfigsFromBytes(configBytes []byte) (map[string]e2eSnapshotConfig, error) {
+	var model partialE2EWorkflowMetaModel
+	if err := yaml.Unmarshal(configBytes, &model); err != nil {
+		return nil, err
+	}
+
+	configs := map[string]e2eSnapshotConfig{}
+	for workflow, workflowModel := range model.Workflows {
+		config := workflowModel.Meta.StepsCheck.Snapshot
+		if config == nil {
+			continue
+		}
+
+		for _, file := range config.Files {
+			if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+				return nil, fmt.Errorf("workflow %s: snapshot file (%s) must be relative to the step directory", workflow, file)
+			}
+		}
+
+		configs[workflow] = *config
+	}
+
+	return configs, nil
+}
+
+// e2eSnapshotter compares the outputs and files of E2E workflow runs with the golden files in e2e/snapshots,
+// or rewrites the golden files in update mode.
+type e2eSnapshotter struct {
+	workDir        string
+	snapshotsDir   string
+	stepOutputKeys []string
+	update         bool
+	mismatches     string
+	// removed are the stale golden files removed in update mode, relative to the snapshots directory.
+	removed []string
+}
+
+func newE2ESnapshotter(workDir string, stepOutputKeys []string, update bool) *e2eSnapshotter {
+	return &e2eSnapshotter{
+		workDir:        workDir,
+		snapshotsDir:   filepath.Join(workDir, "e2e", snapshotsDirName),
+		stepOutputKeys: stepOutputKeys,
+		update:         update,
+	}
+}
+
+func (s *e2eSnapshotter) check(workflow string, config e2eSnapshotConfig, result e2eRunResult) error {
+	actual, err := s.collect(config, result)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range actual {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if s.update {
+		if err := s.removeStale(filepath.Join(s.snapshotsDir, workflow), actual); err != nil {
+			return err
+		}
+	}
+
+	var failed []string
+	for _, name := range names {
+		goldenPath := filepath.Join(s.snapshotsDir, workflow, name)
+		if s.update {
+			if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(goldenPath, []byte(actual[name]), 0644); err != nil {
+				return err
+			}
+			continue
+		}
+
+		golden, err := ioutil.ReadFile(goldenPath)
+		if os.IsNotExist(err) {
+			failed = append(failed, name)
+			s.mismatches += fmt.Sprintf("- %s: %s has no snapshot, run with update_snapshots: \"yes\" to create it\n", colorstring.Yellow(workflow), name)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if diff := unifiedDiff("snapshot/"+name, "actual/"+name, string(golden), actual[name]); diff != "" {
+			failed = append(failed, name)
+			s.mismatches += fmt.Sprintf("- %s: %s differs from snapshot\n%s", colorstring.Yellow(workflow), name, diff)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", errSnapshotMismatch, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// removeStaleWorkflows removes the golden files of the workflows, which no longer have a snapshot config.
+func (s *e2eSnapshotter) removeStaleWorkflows(configs map[string]e2eSnapshotConfig) error {
+	entries, err := ioutil.ReadDir(s.snapshotsDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if _, ok := configs[entry.Name()]; ok {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.snapshotsDir, entry.Name())); err != nil {
+			return err
+		}
+		s.removed = append(s.removed, entry.Name())
+	}
+	return nil
+}
+
+// removeStale removes the golden files of the workflow's directory, which are not among the actual snapshots.
+func (s *e2eSnapshotter) removeStale(workflowDir string, actual map[string]string) error {
+	var stale []string
+	if err := filepath.Walk(workflowDir, func(pth string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(workflowDir, pth)
+		if err != nil {
+			return err
+		}
+		if _, ok := actual[name]; !ok {
+			stale = append(stale, pth)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, pth := range stale {
+		if err := os.Remove(pth); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.snapshotsDir, pth)
+		if err != nil {
+			return err
+		}
+		s.removed = append(s.removed, rel)
+		// Remove the directories left empty, up to the workflow's directory
+		for dir := filepath.Dir(pth); dir != workflowDir; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// collect returns the snapshot contents by snapshot file name.
+func (s *e2eSnapshotter) collect(config e2eSnapshotConfig, result e2eRunResult) (map[string]string, error) {
+	keys := config.Outputs
+	if len(keys) == 0 {
+		keys = s.stepOutputKeys
+	}
+
+	actual := map[string]string{}
+	if len(keys) > 0 {
+		outputs := map[string]*string{}
+		for _, key := range keys {
+			if value, ok := result.Outputs[key]; ok {
+				outputs[key] = &value
+			} else {
+				outputs[key] = nil
+			}
+		}
+
+		outputsBytes, err := json.MarshalIndent(outputs, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		actual[outputsSnapshotFileName] = string(outputsBytes) + "\n"
+	}
+
+	for _, file := range config.Files {
+		content, err := ioutil.ReadFile(filepath.Join(s.workDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+		}
+		actual[filepath.Join(filesSnapshotDirName, filepath.Clean(file))] = string(content)
+	}
+
+	return actual, nil
+}
+
+func (s *e2eSnapshotter) summary() string {
+	if s.update {
+		summary := fmt.Sprintf("Snapshots updated in %s", s.snapshotsDir)
+		if len(s.removed) > 0 {
+			summary += fmt.Sprintf(", removed stale snapshots: %s", strings.Join(s.removed, ", "))
+		}
+		return summary
+	}
+	if s.mismatches == "" {
+		return "All snapshots match"
+	}
+	return s.mismatches
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readE2ESnapshotConfigsFromBytes(t *testing.T) {
+	configs, err := readE2ESnapshotConfigsFromBytes([]byte(`workflows:
+  test_snapshot:
+    meta:
+      steps-check:
+        snapshot:
+          outputs:
+          - EXPORTED_PATH
+          files:
+          - _tmp/result.json
+  test_plain: {}
+`))
+	if err != nil {
+		t.Fatalf("readE2ESnapshotConfigsFromBytes() error = %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("readE2ESnapshotConfigsFromBytes() got %d configs, want 1", len(configs))
+	}
+	if got := configs["test_snapshot"].Files; len(got) != 1 || got[0] != "_tmp/result.json" {
+		t.Errorf("readE2ESnapshotConfigsFromBytes() got files = %v", got)
+	}
+
+	if _, err := readE2ESnapshotConfigsFromBytes([]byte(`workflows:
+  test_snapshot:
+    meta:
+      steps-check:
+        snapshot:
+          files:
+          - ../outside.json
+`)); err == nil {
+		t.Errorf("readE2ESnapshotConfigsFromBytes() expected error for file outside of the step directory")
+	}
+}
+
+func Test_e2eSnapshotter_check(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, "_tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	resultPath := filepath.Join(workDir, "_tmp", "result.json")
+	if err := ioutil.WriteFile(resultPath, []byte("{\"a\": 1}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := e2eSnapshotConfig{Files: []string{"_tmp/result.json"}}
+	result := e2eRunResult{Outputs: map[string]string{"EXPORTED_PATH": "/path"}}
+
+	snapshotter := newE2ESnapshotter(workDir, []string{"EXPORTED_PATH"}, false)
+	if err := snapshotter.check("test_snapshot", config, result); err == nil {
+		t.Errorf("check() expected error for missing snapshots")
+	}
+
+	updater := newE2ESnapshotter(workDir, []string{"EXPORTED_PATH"}, true)
+	if err := updater.check("test_snapshot", config, result); err != nil {
+		t.Fatalf("check() in update mode error = %v", err)
+	}
+
+	snapshotter = newE2ESnapshotter(workDir, []string{"EXPORTED_PATH"}, false)
+	if err := snapshotter.check("test_snapshot", config, result); err != nil {
+		t.Errorf("check() error = %v", err)
+	}
+
+	if err := ioutil.WriteFile(resultPath, []byte("{\"a\": 2}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshotter.check("test_snapshot", config, result); err == nil {
+		t.Errorf("check() expected error for changed file")
+	}
+	if summary := snapshotter.summary(); !strings.Contains(summary, "-{\"a\": 1}\n+{\"a\": 2}") {
+		t.Errorf("summary() does not contain the diff: %s", summary)
+	}
+}
+
+func Test_e2eSnapshotter_removesStaleSnapshots(t *testing.T) {
+	workDir := t.TempDir()
+	snapshotsDir := filepath.Join(workDir, "e2e", snapshotsDirName)
+	for _, pth := range []string{
+		"test_snapshot/outputs.json",
+		"test_snapshot/files/_tmp/removed.json",
+		"test_removed/outputs.json",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(snapshotsDir, pth)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(snapshotsDir, pth), []byte("{}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := e2eSnapshotConfig{Outputs: []string{"EXPORTED_PATH"}}
+	updater := newE2ESnapshotter(workDir, nil, true)
+	if err := updater.removeStaleWorkflows(map[string]e2eSnapshotConfig{"test_snapshot": config}); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.check("test_snapshot", config, e2eRunResult{Outputs: map[string]string{"EXPORTED_PATH": "/path"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if err := filepath.Walk(snapshotsDir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(snapshotsDir, pth)
+		if err != nil {
+			return err
+		}
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".", "test_snapshot", "test_snapshot/outputs.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots after update = %v, want %v", got, want)
+	}
+	if summary := updater.summary(); !strings.Contains(summary, "removed stale snapshots: test_removed, test_snapshot/files/_tmp/removed.json") {
+		t.Errorf("summary() = %s, want the removed snapshots", summary)
+	}
+}
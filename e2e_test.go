@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"os/exec"
 	"reflect"
+	"runtime"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/errorutil"
 )
 
 func Test_readE2EWorkflowsFromBytes(t *testing.T) {
@@ -142,3 +150,70 @@ workflows:
 		})
 	}
 }
+
+func Test_runWithTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	cmd := exec.Command("sleep", "0")
+	cmd.Stdin = os.Stdin
+	if err := runWithTimeout(cmd, time.Minute); err != nil {
+		t.Errorf("runWithTimeout() error = %v", err)
+	}
+	if cmd.Stdin != nil {
+		t.Errorf("runWithTimeout() should not pass stdin to the command's process group")
+	}
+
+	err := runWithTimeout(exec.Command("sleep", "10"), 50*time.Millisecond)
+	if err == nil || err.Error() != "timed out after 50ms" {
+		t.Errorf("runWithTimeout() error = %v, want a timeout", err)
+	}
+	if errorutil.IsExitStatusError(err) {
+		t.Errorf("runWithTimeout() returned an exit status error on timeout")
+	}
+}
+
+func Test_runWithTimeout_childProcesses(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil || runtime.GOOS == "windows" {
+		t.Skip("child processes are only killed on unix")
+	}
+
+	// The child process holds the output open, waiting for it would block until it exits
+	cmd := exec.Command("sh", "-c", "sleep 10 & sleep 10")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	start := time.Now()
+	if err := runWithTimeout(cmd, 50*time.Millisecond); err == nil {
+		t.Errorf("runWithTimeout() error = nil, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runWithTimeout() returned after %s, want the child processes killed", elapsed)
+	}
+}
+
+func Test_forwardSignals(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil || runtime.GOOS == "windows" {
+		t.Skip("signals are only forwarded on unix")
+	}
+
+	cmd := exec.Command("sleep", "10")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stopForwarding := forwardSignals(cmd)
+	start := time.Now()
+	if err := raiseSignal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("Wait() error = nil, want the command terminated")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command exited after %s, want it terminated by the forwarded signal", elapsed)
+	}
+	if sig := stopForwarding(); sig != syscall.SIGTERM {
+		t.Errorf("stopForwarding() got = %v, want %v", sig, syscall.SIGTERM)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// e2eResourceUsage is the resource usage of an E2E workflow's process tree,
+// including all descendant processes which were waited for.
+type e2eResourceUsage struct {
+	UserCPU   time.Duration
+	SystemCPU time.Duration
+	// MaxRSS is the peak resident set size of the largest process in the tree, in bytes.
+	MaxRSS int64
+}
+
+func processResourceUsage(state *os.ProcessState) e2eResourceUsage {
+	if state == nil {
+		return e2eResourceUsage{}
+	}
+
+	return e2eResourceUsage{
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+		MaxRSS:    maxRSS(state),
+	}
+}
+
+func (u e2eResourceUsage) summary(wallTime time.Duration) string {
+	return fmt.Sprintf("wall: %s, cpu: %s (user: %s, sys: %s), peak RSS: %s",
+		wallTime.Round(time.Millisecond),
+		(u.UserCPU + u.SystemCPU).Round(time.Millisecond),
+		u.UserCPU.Round(time.Millisecond),
+		u.SystemCPU.Round(time.Millisecond),
+		formatBytes(u.MaxRSS),
+	)
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
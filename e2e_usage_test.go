@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_e2eResourceUsage_summary(t *testing.T) {
+	tests := []struct {
+		name     string
+		usage    e2eResourceUsage
+		wallTime time.Duration
+		want     string
+	}{
+		{
+			"No usage",
+			e2eResourceUsage{},
+			1500 * time.Millisecond,
+			"wall: 1.5s, cpu: 0s (user: 0s, sys: 0s), peak RSS: 0 B",
+		},
+		{
+			"Usage",
+			e2eResourceUsage{UserCPU: 2 * time.Second, SystemCPU: 250 * time.Millisecond, MaxRSS: 512 * 1024 * 1024},
+			3 * time.Second,
+			"wall: 3s, cpu: 2.25s (user: 2s, sys: 250ms), peak RSS: 512.0 MiB",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.usage.summary(tt.wallTime); got != tt.want {
+				t.Errorf("summary() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
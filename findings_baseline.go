@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/errorutil"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	defaultFindingsBaselinePath = ".steps-check-baseline.json"
+	findingsBaselineVersion     = 1
+	staleBaselineEntryRule      = "stale-entry"
+)
+
+// findingsBaselineFile is the format of the baseline file, recording the accepted findings of a step repo.
+// A finding recorded several times is accepted as many times.
+type findingsBaselineFile struct {
+	Version  int                     `json:"version"`
+	Findings []findingsBaselineEntry `json:"findings"`
+}
+
+// findingsBaselineEntry is an accepted finding. Only the fingerprint is matched, the rest helps reviewing the file.
+type findingsBaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Check       string `json:"check"`
+	Rule        string `json:"rule,omitempty"`
+	File        string `json:"file"`
+	Message     string `json:"message"`
+}
+
+// findingsBaseline marks the diagnostics matching the baseline file's findings, and tracks the unmatched entries.
+type findingsBaseline struct {
+	dir       string
+	pth       string
+	entries   []findingsBaselineEntry
+	matched   []bool
+	checksRun map[string]bool
+	// lines caches the lines of the files by their relative path, used for fingerprinting.
+	lines map[string][]string
+}
+
+func newFindingsBaseline(dir, pth string, entries []findingsBaselineEntry) *findingsBaseline {
+	return &findingsBaseline{
+		dir:       dir,
+		pth:       pth,
+		entries:   entries,
+		matched:   make([]bool, len(entries)),
+		checksRun: map[string]bool{},
+		lines:     map[string][]string{},
+	}
+}
+
+// findingsBaselinePath returns the path of the repo's baseline file, configurable in the repo config.
+func findingsBaselinePath(dir string, repoCfg repoConfig) string {
+	if repoCfg.Baseline != "" {
+		return filepath.Join(dir, repoCfg.Baseline)
+	}
+	return filepath.Join(dir, defaultFindingsBaselinePath)
+}
+
+// readFindingsBaseline reads the baseline file of the repo directory, nil is returned if it doesn't exist.
+func readFindingsBaseline(dir string, repoCfg repoConfig) (*findingsBaseline, error) {
+	pth := findingsBaselinePath(dir, repoCfg)
+	baselineBytes, err := ioutil.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file findingsBaselineFile
+	if err := json.Unmarshal(baselineBytes, &file); err != nil {
+		return nil, fmt.Errorf("invalid findings baseline (%s): %w", pth, err)
+	}
+	if file.Version != findingsBaselineVersion {
+		return nil, fmt.Errorf("unsupported findings baseline version (%s): %d", pth, file.Version)
+	}
+	return newFindingsBaseline(dir, pth, file.Findings), nil
+}
+
+// writeFindingsBaseline records the diagnostics into the repo's baseline file, sorted to keep its diffs small.
+func writeFindingsBaseline(dir string, repoCfg repoConfig, diagnostics []diagnostic) (string, error) {
+	baseline := newFindingsBaseline(dir, findingsBaselinePath(dir, repoCfg), nil)
+	file := findingsBaselineFile{Version: findingsBaselineVersion, Findings: []findingsBaselineEntry{}}
+	for _, d := range diagnostics {
+		file.Findings = append(file.Findings, findingsBaselineEntry{
+			Fingerprint: baseline.fingerprint(d),
+			Check:       d.Check,
+			Rule:        d.Rule,
+			File:        d.File,
+			Message:     d.Message,
+		})
+	}
+	sort.SliceStable(file.Findings, func(i, j int) bool {
+		a, b := file.Findings[i], file.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Message != b.Message {
+			return a.Message < b.Message
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+
+	baselineBytes, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(baseline.pth, append(baselineBytes, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return baseline.pth, nil
+}
+
+// fingerprint identifies the diagnostic by its check, rule, file, message and the trimmed content of its line,
+// instead of the line number, so the fingerprint survives changes shifting the lines of the file.
+func (b *findingsBaseline) fingerprint(d diagnostic) string {
+	var lineContent string
+	if d.Line > 0 {
+		lines := b.fileLines(d.File)
+		if d.Line <= len(lines) {
+			lineContent = strings.TrimSpace(lines[d.Line-1])
+		}
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join([]string{d.Check, d.Rule, d.File, d.Message, lineContent}, "\x00")))
+	return hex.EncodeToString(hash[:16])
+}
+
+func (b *findingsBaseline) fileLines(file string) []string {
+	if lines, ok := b.lines[file]; ok {
+		return lines
+	}
+
+	var lines []string
+	if content, err := ioutil.ReadFile(filepath.Join(b.dir, filepath.FromSlash(file))); err == nil {
+		lines = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	}
+	b.lines[file] = lines
+	return lines
+}
+
+// mark marks the diagnostics of the check, which match an unmatched baseline entry.
+// The diagnostic paths need to be relative to the repo directory.
+func (b *findingsBaseline) mark(check string, diagnostics []diagnostic) []diagnostic {
+	b.checksRun[check] = true
+	for i, d := range diagnostics {
+		fingerprint := b.fingerprint(d)
+		for j, entry := range b.entries {
+			if !b.matched[j] && entry.Fingerprint == fingerprint {
+				b.matched[j] = true
+				diagnostics[i].Baselined = true
+				break
+			}
+		}
+	}
+	return diagnostics
+}
+
+// staleDiagnostics reports the entries of the checks run, which did not match any finding.
+// The entries of the checks, which did not run, can not be considered stale.
+func (b *findingsBaseline) staleDiagnostics() []diagnostic {
+	file, err := filepath.Rel(b.dir, b.pth)
+	if err != nil {
+		file = b.pth
+	}
+
+	var diagnostics []diagnostic
+	for i, entry := range b.entries {
+		if b.matched[i] || !b.checksRun[entry.Check] {
+			continue
+		}
+
+		id := entry.Check
+		if entry.Rule != "" {
+			id += "/" + entry.Rule
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Check:    checkBaseline,
+			Rule:     staleBaselineEntryRule,
+			File:     filepath.ToSlash(file),
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("%s is fixed in %s: %s", id, entry.File, entry.Message),
+			Fix:      "remove the entry, or run steps-check baseline to record the current findings",
+		})
+	}
+	return diagnostics
+}
+
+// withFindingsBaseline marks the diagnostics of the check, which are in the baseline. The check is returned as is
+// if there is no baseline.
+func withFindingsBaseline(b *findingsBaseline, check nativeCheck) nativeCheck {
+	if b == nil {
+		return check
+	}
+
+	run := check.run
+	check.run = func() ([]diagnostic, error) {
+		diagnostics, err := run()
+		if err != nil {
+			return nil, err
+		}
+		return b.mark(check.id, diagnostics), nil
+	}
+	return check
+}
+
+// workflowCheckRan reports if the workflow ran the check of its findings, only then the check's baseline entries
+// can be fixed. The lint workflow skips golangci-lint if it is disabled.
+func workflowCheckRan(workflow string, enabled map[string]bool) bool {
+	return workflow != lintWorkflow || enabled[checkGolangciLint]
+}
+
+// baselinedWorkflowError decides the failure of the workflow by its error findings, which are not in the baseline.
+// Only the lint workflow's failure is explained by its findings, go test fails for reasons (like build errors or
+// panics) without a finding, so the exit status of the other workflows decides.
+func baselinedWorkflowError(workflow string, err error, diagnostics []diagnostic) error {
+	errorCount, baselinedCount := 0, 0
+	for _, d := range diagnostics {
+		switch {
+		case d.Severity != severityError:
+		case d.Baselined:
+			baselinedCount++
+		default:
+			errorCount++
+		}
+	}
+
+	if workflow == lintWorkflow && errorCount == 0 && baselinedCount > 0 && errorutil.IsExitStatusError(err) {
+		log.Donef("Workflow %s failed with %d baselined finding(s) only", workflow, baselinedCount)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_findingsBaseline(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "bitrise.yml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	braces := func(line int) diagnostic {
+		return diagnostic{Check: checkYAMLLint, Rule: "braces", File: "bitrise.yml", Line: line, Column: 4, Severity: severityError, Message: "too few spaces inside empty braces"}
+	}
+
+	writeFile("a: {}\nb: {}\n")
+	recorded := []diagnostic{
+		braces(1),
+		braces(2),
+		{Check: checkAudit, Rule: "url", File: "step.yml", Line: 3, Severity: severityError, Message: "invalid URL"},
+		{Check: checkREADMEDiff, Rule: "missing-readme", File: "README.md", Severity: severityError, Message: "no README.md found"},
+	}
+	if _, err := writeFindingsBaseline(dir, repoConfig{}, recorded); err != nil {
+		t.Fatalf("writeFindingsBaseline() error = %v", err)
+	}
+
+	// Lines shifted, the finding of b is fixed, c is new
+	writeFile("# comment\n\na: {}\nb: { }\nc: {}\n")
+	baseline, err := readFindingsBaseline(dir, repoConfig{})
+	if err != nil || baseline == nil {
+		t.Fatalf("readFindingsBaseline() = %v, %v", baseline, err)
+	}
+
+	got := baseline.mark(checkYAMLLint, []diagnostic{braces(3), braces(5)})
+	var baselined []bool
+	for _, d := range got {
+		baselined = append(baselined, d.Baselined)
+	}
+	if want := []bool{true, false}; !reflect.DeepEqual(baselined, want) {
+		t.Errorf("mark() baselined = %v, want %v", baselined, want)
+	}
+
+	baseline.mark(checkREADMEDiff, []diagnostic{recorded[3]})
+
+	// The audit didn't run, its entry is not stale
+	stale := baseline.staleDiagnostics()
+	if len(stale) != 1 {
+		t.Fatalf("staleDiagnostics() = %v, want 1 diagnostic", stale)
+	}
+	wantStale := diagnostic{
+		Check:    checkBaseline,
+		Rule:     staleBaselineEntryRule,
+		File:     defaultFindingsBaselinePath,
+		Severity: severityWarning,
+		Message:  "yamllint/braces is fixed in bitrise.yml: too few spaces inside empty braces",
+		Fix:      "remove the entry, or run steps-check baseline to record the current findings",
+	}
+	if !reflect.DeepEqual(stale[0], wantStale) {
+		t.Errorf("staleDiagnostics() = %#v, want %#v", stale[0], wantStale)
+	}
+}
+
+func Test_findingsBaseline_duplicates(t *testing.T) {
+	dir := t.TempDir()
+	d := diagnostic{Check: checkAudit, Rule: "url", File: "step.yml", Severity: severityError, Message: "invalid URL"}
+	if _, err := writeFindingsBaseline(dir, repoConfig{Baseline: "baseline.json"}, []diagnostic{d}); err != nil {
+		t.Fatal(err)
+	}
+
+	baseline, err := readFindingsBaseline(dir, repoConfig{Baseline: "baseline.json"})
+	if err != nil || baseline == nil {
+		t.Fatalf("readFindingsBaseline() = %v, %v", baseline, err)
+	}
+
+	got := baseline.mark(checkAudit, []diagnostic{d, d})
+	if !got[0].Baselined || got[1].Baselined {
+		t.Errorf("mark() = %#v, want only the first finding baselined", got)
+	}
+}
+
+func Test_readFindingsBaseline(t *testing.T) {
+	dir := t.TempDir()
+	if baseline, err := readFindingsBaseline(dir, repoConfig{}); baseline != nil || err != nil {
+		t.Errorf("readFindingsBaseline() = %v, %v, want no baseline", baseline, err)
+	}
+
+	pth := filepath.Join(dir, defaultFindingsBaselinePath)
+	if err := ioutil.WriteFile(pth, []byte(`{"version": 2, "findings": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFindingsBaseline(dir, repoConfig{}); err == nil || !strings.Contains(err.Error(), "unsupported findings baseline version") {
+		t.Errorf("readFindingsBaseline() error = %v", err)
+	}
+}
+
+func Test_reportDiagnostics_baselined(t *testing.T) {
+	diagnostics := []diagnostic{
+		{Check: checkAudit, Rule: "url", File: "step.yml", Severity: severityError, Message: "invalid URL", Baselined: true},
+		{Check: checkAudit, Rule: "description", File: "step.yml", Severity: severityWarning, Message: "missing description"},
+	}
+	if err := reportDiagnostics(auditCheckTitle, diagnostics); err != nil {
+		t.Errorf("reportDiagnostics() error = %v, want baselined errors to pass", err)
+	}
+
+	diagnostics[0].Baselined = false
+	if err := reportDiagnostics(auditCheckTitle, diagnostics); err == nil {
+		t.Errorf("reportDiagnostics() passed with a new error")
+	}
+}
+
+func Test_newSARIFLog_baselineState(t *testing.T) {
+	diagnostics := []diagnostic{
+		{Check: checkAudit, Rule: "url", File: "step.yml", Line: 3, Severity: severityError, Message: "invalid URL", Baselined: true},
+		{Check: checkAudit, Rule: "url", File: "step.yml", Line: 5, Severity: severityError, Message: "invalid URL"},
+	}
+
+	var states []string
+	for _, result := range newSARIFLog("", diagnostics, true).Runs[0].Results {
+		states = append(states, result.BaselineState)
+	}
+	if want := []string{"unchanged", "new"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("newSARIFLog() baseline states = %v, want %v", states, want)
+	}
+}
+
+func Test_baselinedWorkflowError(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	baselined := diagnostic{Check: checkGolangciLint, Rule: "revive", File: "main.go", Severity: severityError, Baselined: true}
+	newError := diagnostic{Check: checkGolangciLint, Rule: "revive", File: "main.go", Severity: severityError}
+	warning := diagnostic{Check: checkGolangciLint, Rule: "revive", File: "main.go", Severity: severityWarning}
+
+	tests := []struct {
+		name        string
+		workflow    string
+		err         error
+		diagnostics []diagnostic
+		wantErr     bool
+	}{
+		{"Passed", lintWorkflow, nil, nil, false},
+		{"Only baselined findings", lintWorkflow, exitErr, []diagnostic{baselined, warning}, false},
+		{"New finding", lintWorkflow, exitErr, []diagnostic{baselined, newError}, true},
+		{"Failed without findings", lintWorkflow, exitErr, []diagnostic{warning}, true},
+		{"Timed out", lintWorkflow, errors.New("timed out after 1m0s"), []diagnostic{baselined}, true},
+		{"Failed tests", unitTestWorkflow, exitErr, []diagnostic{baselined}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := baselinedWorkflowError(tt.workflow, tt.err, tt.diagnostics); (err != nil) != tt.wantErr {
+				t.Errorf("baselinedWorkflowError() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_workflowCheckRan(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow string
+		enabled  map[string]bool
+		want     bool
+	}{
+		{"Lint with golangci-lint", lintWorkflow, map[string]bool{checkGolangciLint: true}, true},
+		{"Lint without golangci-lint", lintWorkflow, map[string]bool{checkGolangciLint: false}, false},
+		{"Unit tests without golangci-lint", unitTestWorkflow, map[string]bool{checkGolangciLint: false}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workflowCheckRan(tt.workflow, tt.enabled); got != tt.want {
+				t.Errorf("workflowCheckRan() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runGolangciLint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake golangci-lint is a shell script")
+	}
+
+	binDir := t.TempDir()
+	fake := "#!/bin/sh\necho 'main.go:3:1: exported function Run should have comment (revive)'\nexit 1\n"
+	if err := ioutil.WriteFile(filepath.Join(binDir, "golangci-lint"), []byte(fake), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	got, err := runGolangciLint(t.TempDir())
+	if err != nil {
+		t.Fatalf("runGolangciLint() error = %v", err)
+	}
+	want := []diagnostic{{Check: checkGolangciLint, Rule: "revive", File: "main.go", Line: 3, Column: 1, Severity: severityError, Message: "exported function Run should have comment"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runGolangciLint() got = %#v, want %#v", got, want)
+	}
+}
@@ -1,21 +1,26 @@
 package main
 
 import (
+	"bytes"
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-steputils/tools"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/env"
 	"github.com/bitrise-io/go-utils/errorutil"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
 	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/segmentio/analytics-go"
 )
 
 //go:embed checks.bitrise.yml
@@ -24,19 +29,43 @@ var checkConfig string
 //go:embed .yamllint.yml
 var yamllintConfig string
 
+//go:embed .ymlfmt
+var yamlfmtConfig string
+
 const e2eWorkflow = "e2e"
-const yamllintEnvKey = "YAMLLINT_CONFIG_FILE"
+const lintWorkflow = "lint"
+const unitTestWorkflow = "unit_test"
+
+// Step outputs exposing the paths of the reports of the findings.
+const (
+	sarifPathOutputKey   = "STEPS_CHECK_SARIF_PATH"
+	summaryPathOutputKey = "STEPS_CHECK_SUMMARY_PATH"
+)
 
 // Config ...
 type Config struct {
-	WorkDir               string   `env:"step_dir,dir"`
-	Workflow              []string `env:"workflow,multiline"`
-	SkipStepYMLValidation bool     `env:"skip_step_yml_validation,opt[yes,no]"`
-	SkipGoChecks          bool     `env:"skip_go_checks,opt[yes,no]"`
-	SegmentWriteKey       string   `env:"SEGMENT_WRITE_KEY"`
-	ParentBuildURL        string   `env:"PARENT_BUILD_URL"`
-	IsCI                  bool     `env:"CI"`
-	IsPR                  bool     `env:"PR"`
+	WorkDir                string   `env:"step_dir,dir"`
+	Workflow               []string `env:"workflow,multiline"`
+	SkipStepYMLValidation  *bool    `env:"skip_step_yml_validation,opt[yes,no,]"`
+	SkipGoChecks           *bool    `env:"skip_go_checks,opt[yes,no,]"`
+	E2EDiffPreviousRelease bool     `env:"e2e_diff_previous_release,opt[yes,no]"`
+	UpdateSnapshots        bool     `env:"update_snapshots,opt[yes,no]"`
+	E2EShuffle             bool     `env:"e2e_shuffle,opt[yes,no]"`
+	E2EShuffleSeed         string   `env:"e2e_shuffle_seed"`
+	E2EFailurePolicy       string   `env:"e2e_failure_policy"`
+	E2ESecretsEnvPrefix    string   `env:"e2e_secrets_env_prefix"`
+	E2ERequiredSecrets     []string `env:"e2e_required_secrets,multiline"`
+	E2EDurationBaseline    string   `env:"e2e_duration_baseline"`
+	E2EDurationThreshold   *float64 `env:"e2e_duration_threshold"`
+	E2EDurationFails       bool     `env:"e2e_duration_regression_fails,opt[yes,no]"`
+	CheckYAMLFormatting    *bool    `env:"check_yaml_formatting,opt[yes,no,]"`
+	OutputFormat           string   `env:"output_format,opt[text,github]"`
+	DeployDir              string   `env:"BITRISE_DEPLOY_DIR"`
+	SegmentWriteKey        string   `env:"SEGMENT_WRITE_KEY"`
+	ParentBuildURL         string   `env:"PARENT_BUILD_URL"`
+	IsCI                   bool     `env:"CI"`
+	IsPR                   bool     `env:"PR"`
+	IsScheduledCIRun       bool     `env:"CI_RUN"`
 }
 
 func mainR() error {
@@ -71,10 +100,108 @@ func mainR() error {
 		return fmt.Errorf("failed to change working directory (%s): %v", config.WorkDir, err)
 	}
 
+	repoCfg, err := readRepoConfig(config.WorkDir)
+	if err != nil {
+		return err
+	}
+	enabled := enabledChecks(config, repoCfg)
+	if config.IsScheduledCIRun {
+		log.Infof("Running in scheduled batch CI, skipping the %s", readmeCheckTitle)
+	}
+	baseline, err := readFindingsBaseline(config.WorkDir, repoCfg)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return err
+	}
+
+	reproDir := config.DeployDir
+	if reproDir == "" {
+		reproDir = tmpDir
+	}
+
+	var analyticsClient analytics.Client
+	var analyticsCtx analyticsContext
+	if config.ParentBuildURL != "" && config.SegmentWriteKey != "" {
+		analyticsClient = analytics.New(config.SegmentWriteKey)
+		defer analyticsClient.Close()
+		analyticsCtx = newAnalyticsContext(commandFactory, config.WorkDir)
+	}
+	sendCheckEvent := func(workflow string, err error, duration time.Duration) error {
+		if analyticsClient == nil {
+			return nil
+		}
+		return sendCheckAnalytics(analyticsClient, analyticsCtx, config.ParentBuildURL, workflow, err, duration)
+	}
+
+	// Diagnostics of the native checks and the ones parsed from the workflow outputs, summarized at the end
+	var diagnostics []diagnostic
+	var results []checkResult
+	defer func() {
+		if baseline != nil {
+			diagnostics = append(diagnostics, baseline.staleDiagnostics()...)
+		}
+		printDiagnosticsSummary(diagnostics)
+		if config.OutputFormat == outputFormatGitHub {
+			printGitHubAnnotations(diagnostics)
+		}
+
+		if sarifPath, err := writeSARIF(reproDir, config.WorkDir, diagnostics, baseline != nil); err != nil {
+			log.Warnf("Failed to write SARIF file: %s", err)
+		} else {
+			exportReportPath(sarifPathOutputKey, sarifPath)
+		}
+		if summaryPath, err := writeMarkdownSummary(reproDir, results, diagnostics); err != nil {
+			log.Warnf("Failed to write Markdown summary: %s", err)
+		} else {
+			exportReportPath(summaryPathOutputKey, summaryPath)
+		}
+	}()
+
 	if runE2EWorkflow {
 		log.Donef("Running '%s' workflow", e2eWorkflow)
-		shouldFailOnFirstError := !config.IsCI || config.IsPR
-		if err := runE2E(commandFactory, config.WorkDir, shouldFailOnFirstError, config.SegmentWriteKey, config.ParentBuildURL); err != nil {
+		failurePolicy, err := parseE2EFailurePolicy(config.E2EFailurePolicy, config.IsCI, config.IsPR)
+		if err != nil {
+			return err
+		}
+		log.Infof("E2E failure policy: %s (%s)", failurePolicy, failurePolicy.Reason)
+
+		durationThreshold := defaultE2EDurationThreshold
+		if config.E2EDurationThreshold != nil {
+			durationThreshold = *config.E2EDurationThreshold
+		}
+		if durationThreshold <= 0 {
+			return fmt.Errorf("e2e_duration_threshold must be greater than 0, got %g", durationThreshold)
+		}
+
+		opts := e2eOptions{
+			FailurePolicy:           failurePolicy,
+			Analytics:               analyticsClient,
+			AnalyticsContext:        analyticsCtx,
+			ParentURL:               config.ParentBuildURL,
+			DiffPreviousRelease:     config.E2EDiffPreviousRelease,
+			UpdateSnapshots:         config.UpdateSnapshots,
+			Shuffle:                 config.E2EShuffle,
+			ShuffleSeed:             config.E2EShuffleSeed,
+			SecretsEnvPrefix:        config.E2ESecretsEnvPrefix,
+			RequiredSecrets:         config.E2ERequiredSecrets,
+			ReproDir:                reproDir,
+			DurationBaselinePath:    config.E2EDurationBaseline,
+			DurationThreshold:       durationThreshold,
+			DurationRegressionFails: config.E2EDurationFails,
+			Include:                 repoCfg.E2E.Include,
+			Exclude:                 repoCfg.E2E.Exclude,
+			TestTimeout:             repoCfg.E2E.Timeout,
+		}
+		duration, err := runE2E(commandFactory, config.WorkDir, opts)
+		results = append(results, newCheckResult(e2eWorkflow, err, duration, nil))
+		if sendErr := sendCheckEvent(e2eWorkflow, err, duration); sendErr != nil {
+			return sendErr
+		}
+		if err != nil {
 			return fmt.Errorf("workflow %s failed: %w", e2eWorkflow, err)
 		}
 
@@ -82,47 +209,95 @@ func mainR() error {
 	}
 
 	// Run other, non-e2e workflows
-	tmpDir, err := ioutil.TempDir("", "")
-	if err != nil {
-		return err
-	}
-
 	configPath := filepath.Join(tmpDir, "bitrise.yml")
 	if err := ioutil.WriteFile(configPath, []byte(checkConfig), 0600); err != nil {
 		return err
 	}
 
-	yamllintPath := filepath.Join(tmpDir, ".yamllint.yml")
-	if err := ioutil.WriteFile(yamllintPath, []byte(yamllintConfig), 0600); err != nil {
-		return err
-	}
-	if err := os.Setenv(yamllintEnvKey, yamllintPath); err != nil {
-		return err
-	}
-
 	for _, wf := range config.Workflow {
-		workflowCmd :=
-			commandFactory.Create(
-				"bitrise",
-				[]string{"run", wf, "--config", configPath},
-				&command.Opts{
-					Dir: config.WorkDir,
-					Env: []string{
-						fmt.Sprintf("STEP_DIR=%s", config.WorkDir),
-						fmt.Sprintf("SKIP_STEP_YML_VALIDATION=%t", config.SkipStepYMLValidation),
-						fmt.Sprintf("SKIP_GO_CHECKS=%t", config.SkipGoChecks),
-					},
-
-					Stdout: os.Stdout,
-					Stderr: os.Stderr,
-				})
+		if wf == unitTestWorkflow && !enabled[checkGoTest] {
+			fmt.Println()
+			log.Infof("Skipping '%s' workflow, the %s check is disabled", wf, checkGoTest)
+			continue
+		}
+
+		envs := []reproEnv{
+			{Key: "STEP_DIR", Value: config.WorkDir},
+			{Key: "SKIP_STEP_YML_VALIDATION", Value: fmt.Sprintf("%t", !enabled[checkAudit])},
+			{Key: "SKIP_GO_CHECKS", Value: fmt.Sprintf("%t", !enabled[checkGolangciLint])},
+		}
+		var cmdEnvs []string
+		for _, env := range envs {
+			cmdEnvs = append(cmdEnvs, fmt.Sprintf("%s=%s", env.Key, env.Value))
+		}
+
+		workflowCmdArgs := []string{"run", wf, "--config", configPath}
+		var workflowOutput bytes.Buffer
+		// command.Command can not be killed, which is needed for the workflow timeout
+		workflowCmd := exec.Command("bitrise", workflowCmdArgs...)
+		workflowCmd.Dir = config.WorkDir
+		workflowCmd.Env = append(os.Environ(), cmdEnvs...)
+		workflowCmd.Stdout = io.MultiWriter(os.Stdout, &workflowOutput)
+		workflowCmd.Stderr = os.Stderr
 		fmt.Println()
-		log.Donef("$ %s", workflowCmd.PrintableCommandArgs())
-		if err := workflowCmd.Run(); err != nil {
-			if errorutil.IsExitStatusError(err) {
-				return fmt.Errorf("workflow %s failed: %w", wf, err)
+		log.Donef("$ %s", printableCommandArgs(workflowCmd.Args))
+		start := time.Now()
+		var nativeErr error
+		if wf == lintWorkflow {
+			// The workflow runs even if a native check failed, so golangci-lint reports its findings too
+			var nativeDiagnostics []diagnostic
+			var nativeResults []checkResult
+			nativeDiagnostics, nativeResults, nativeErr = runNativeLintChecks(config.WorkDir, enabled, repoCfg, baseline, reproDir)
+			diagnostics = append(diagnostics, nativeDiagnostics...)
+			results = append(results, nativeResults...)
+		}
+
+		workflowStart := time.Now()
+		err := runWithTimeout(workflowCmd, repoCfg.WorkflowTimeout)
+		workflowDiagnostics := repoCfg.apply(parseWorkflowDiagnostics(config.WorkDir, wf, workflowOutput.String()))
+		if baseline != nil {
+			if workflowCheckRan(wf, enabled) {
+				baseline.mark(workflowCheckID(wf), workflowDiagnostics)
 			}
-			return fmt.Errorf("failed to run command: %v", err)
+			err = baselinedWorkflowError(wf, err, workflowDiagnostics)
+		}
+		diagnostics = append(diagnostics, workflowDiagnostics...)
+		results = append(results, newCheckResult(wf+" workflow", err, time.Since(workflowStart), workflowDiagnostics))
+
+		checkErr := err
+		if checkErr == nil {
+			checkErr = nativeErr
+		}
+		if sendErr := sendCheckEvent(wf, checkErr, time.Since(start)); sendErr != nil {
+			return sendErr
+		}
+
+		if err != nil {
+			script := reproScript{
+				Name: wf,
+				Dir:  config.WorkDir,
+				Files: []reproFile{
+					{Path: configPath, Content: checkConfig},
+				},
+				Envs:    envs,
+				Command: append([]string{"bitrise"}, workflowCmdArgs...),
+			}
+			if reproPath, reproErr := writeReproScript(reproDir, script); reproErr != nil {
+				log.Warnf("Failed to write reproduction script: %s", reproErr)
+			} else {
+				log.Infof("Reproduce locally with: %s", reproPath)
+			}
+
+			if !errorutil.IsExitStatusError(err) {
+				err = fmt.Errorf("failed to run command: %w", err)
+			}
+			if nativeErr != nil {
+				return fmt.Errorf("workflow %s failed: %v, %w", wf, nativeErr, err)
+			}
+			return fmt.Errorf("workflow %s failed: %w", wf, err)
+		}
+		if nativeErr != nil {
+			return fmt.Errorf("workflow %s failed: %w", wf, nativeErr)
 		}
 
 		log.Donef("Check '%s' succeeded", wf)
@@ -131,8 +306,102 @@ func mainR() error {
 	return nil
 }
 
+// enabledChecks resolves which checks run by their ID: the related step inputs take precedence over the repo config.
+func enabledChecks(config Config, repoCfg repoConfig) map[string]bool {
+	not := func(b *bool) *bool {
+		if b == nil {
+			return nil
+		}
+		negated := !*b
+		return &negated
+	}
+
+	enabled := map[string]bool{
+		checkYAMLLint:     repoCfg.checkEnabled(checkYAMLLint, nil, true),
+		checkYAMLFmt:      repoCfg.checkEnabled(checkYAMLFmt, config.CheckYAMLFormatting, false),
+		checkAudit:        repoCfg.checkEnabled(checkAudit, not(config.SkipStepYMLValidation), true),
+		checkSchema:       repoCfg.checkEnabled(checkSchema, not(config.SkipStepYMLValidation), true),
+		checkREADMEDiff:   repoCfg.checkEnabled(checkREADMEDiff, not(config.SkipStepYMLValidation), true),
+		checkGolangciLint: repoCfg.checkEnabled(checkGolangciLint, not(config.SkipGoChecks), true),
+		checkGoTest:       repoCfg.checkEnabled(checkGoTest, nil, true),
+	}
+	// Scheduled batch CI runs (CI_RUN) check the step repos as they are, without regenerating their READMEs
+	if config.IsScheduledCIRun {
+		enabled[checkREADMEDiff] = false
+	}
+	return enabled
+}
+
+// nativeLintChecks returns the enabled checks of the lint workflow, which are implemented by the step itself.
+// Their diagnostics are relative to the repo directory, with the repo config applied.
+func nativeLintChecks(workDir string, enabled map[string]bool, repoCfg repoConfig) []nativeCheck {
+	stepYMLPath := filepath.Join(workDir, "step.yml")
+	allChecks := []nativeCheck{
+		{checkYAMLLint, yamllintCheckTitle, func() ([]diagnostic, error) { return lintYAMLFiles(workDir, yamllintConfig) }},
+		{checkYAMLFmt, yamlfmtCheckTitle, func() ([]diagnostic, error) { return formatYAMLFiles(workDir, yamlfmtConfig, false) }},
+		{checkAudit, auditCheckTitle, func() ([]diagnostic, error) { return auditStepYML(stepYMLPath) }},
+		{checkSchema, schemaCheckTitle, func() ([]diagnostic, error) { return validateStepSchema(stepYMLPath) }},
+		{checkREADMEDiff, readmeCheckTitle, func() ([]diagnostic, error) { return checkREADME(workDir) }},
+	}
+
+	var checks []nativeCheck
+	for _, check := range allChecks {
+		if enabled[check.id] {
+			checks = append(checks, withRepoConfig(workDir, repoCfg, check))
+		}
+	}
+	return checks
+}
+
+// runNativeLintChecks runs the enabled native checks of the lint workflow, marking the findings in the baseline (if any).
+// All the checks run, the returned error is the first failure. A reproduction script is written into reproDir
+// for each failed check.
+func runNativeLintChecks(workDir string, enabled map[string]bool, repoCfg repoConfig, baseline *findingsBaseline, reproDir string) ([]diagnostic, []checkResult, error) {
+	var checks []nativeCheck
+	for _, check := range nativeLintChecks(workDir, enabled, repoCfg) {
+		checks = append(checks, withFindingsBaseline(baseline, check))
+	}
+
+	var diagnostics []diagnostic
+	var results []checkResult
+	var firstErr error
+	for _, check := range checks {
+		start := time.Now()
+		checkDiagnostics, err := runNativeCheck(check)
+		diagnostics = append(diagnostics, checkDiagnostics...)
+		results = append(results, newCheckResult(check.title, err, time.Since(start), checkDiagnostics))
+		if err == nil {
+			continue
+		}
+
+		if reproPath := writeReproForError(reproDir, check.id, withRepro(err, nativeCheckReproScript(workDir, check.id))); reproPath != "" {
+			log.Infof("Reproduce locally with: %s", reproPath)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return diagnostics, results, firstErr
+}
+
+// exportReportPath exposes the path of a report as a step output.
+func exportReportPath(key, pth string) {
+	if err := tools.ExportEnvironmentWithEnvman(key, pth); err != nil {
+		log.Warnf("Failed to export %s: %s", key, err)
+		return
+	}
+	log.Donef("%s: %s", key, pth)
+}
+
 func main() {
-	if err := mainR(); err != nil {
+	run := mainR
+	if len(os.Args) > 1 {
+		run = func() error {
+			return runCommand(os.Args[1:])
+		}
+	}
+
+	if err := run(); err != nil {
 		log.Errorf("%s", err)
 
 		var exitErr *exec.ExitError
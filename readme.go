@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const readmeFileName = "README.md"
+
+// readmeSectionsTemplate defines a template for each README section, named after the section.
+//
+//go:embed readme_sections.md.tmpl
+var readmeSectionsTemplate string
+
+// readmeSectionNames are the collapsible README sections rendered from step.yml by the readme generator:
+//
+//	<details>
+//	<summary>Inputs</summary>
+//
+//	| Key | Description | Flags | Default |
+//	| --- | --- | --- | --- |
+//	| `step_dir` | Step directory path | required | `.` |
+//	</details>
+var readmeSectionNames = []string{"Description", "Inputs", "Outputs"}
+
+// readmeRules describe the rules of the README check by rule ID.
+var readmeRules = map[string]string{
+	"missing-readme":  "The step must have a README.md",
+	"missing-section": "README.md must have the sections rendered from step.yml",
+	"out-of-date":     "README.md must be up to date with step.yml",
+}
+
+type readmeStepModel struct {
+	Description string                   `yaml:"description"`
+	Inputs      []map[string]interface{} `yaml:"inputs"`
+	Outputs     []map[string]interface{} `yaml:"outputs"`
+}
+
+// readmeStepOptsModel holds the opts of the inputs and outputs, in the same order as readmeStepModel.
+type readmeStepOptsModel struct {
+	Inputs  []readmeEnvModel `yaml:"inputs"`
+	Outputs []readmeEnvModel `yaml:"outputs"`
+}
+
+type readmeEnvModel struct {
+	Opts struct {
+		Title       string `yaml:"title"`
+		Summary     string `yaml:"summary"`
+		Description string `yaml:"description"`
+		IsRequired  bool   `yaml:"is_required"`
+		IsSensitive bool   `yaml:"is_sensitive"`
+	} `yaml:"opts"`
+}
+
+// readmeTemplateModel is the data of the README sections template.
+type readmeTemplateModel struct {
+	Description string
+	Inputs      []readmeTemplateEnv
+	Outputs     []readmeTemplateEnv
+}
+
+// readmeTemplateEnv is a row of the inputs or outputs table, its fields are collapsed into table cells.
+type readmeTemplateEnv struct {
+	Key         string
+	Description string
+	Flags       string
+	Value       string
+}
+
+// checkREADME compares the README sections with the ones rendered from step.yml, the drift is reported
+// as a unified diff in the details of the finding.
+func checkREADME(workDir string) ([]diagnostic, error) {
+	stepBytes, err := ioutil.ReadFile(filepath.Join(workDir, "step.yml"))
+	if err != nil {
+		return nil, err
+	}
+	readmeDiagnostic := diagnostic{
+		Check:    checkREADMEDiff,
+		File:     readmeFileName,
+		Severity: severityError,
+		Fix:      "run the generate_readme workflow and commit the changes",
+	}
+
+	readme, err := ioutil.ReadFile(filepath.Join(workDir, readmeFileName))
+	if os.IsNotExist(err) {
+		readmeDiagnostic.Rule = "missing-readme"
+		readmeDiagnostic.Message = fmt.Sprintf("no %s found", readmeFileName)
+		readmeDiagnostic.Fix = "add the generate_readme workflow, " + readmeDiagnostic.Fix
+		return []diagnostic{readmeDiagnostic}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sections, err := renderREADMESections(stepBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, missing := replaceREADMESections(string(readme), sections)
+	if len(missing) > 0 {
+		readmeDiagnostic.Rule = "missing-section"
+		readmeDiagnostic.Message = fmt.Sprintf("no %s section(s)", strings.Join(missing, ", "))
+		return []diagnostic{readmeDiagnostic}, nil
+	}
+	if diff := unifiedDiff(readmeFileName, readmeFileName+" (expected)", string(readme), expected); diff != "" {
+		readmeDiagnostic.Details = diff
+		readmeDiagnostic.Rule = "out-of-date"
+		readmeDiagnostic.Line = firstChangedLine(string(readme), expected)
+		readmeDiagnostic.Message = "out of date with step.yml"
+		return []diagnostic{readmeDiagnostic}, nil
+	}
+
+	return nil, nil
+}
+
+// renderREADMESections returns the content of the README sections by section name.
+func renderREADMESections(stepBytes []byte) (map[string]string, error) {
+	var step readmeStepModel
+	if err := yaml.Unmarshal(stepBytes, &step); err != nil {
+		return nil, err
+	}
+	var opts readmeStepOptsModel
+	if err := yaml.Unmarshal(stepBytes, &opts); err != nil {
+		return nil, err
+	}
+
+	model := readmeTemplateModel{Description: strings.TrimSpace(step.Description)}
+	for i, input := range step.Inputs {
+		key, value := readmeEnvKeyValue(input)
+		inputOpts := opts.Inputs[i].Opts
+
+		var flags []string
+		if inputOpts.IsRequired {
+			flags = append(flags, "required")
+		}
+		if inputOpts.IsSensitive {
+			flags = append(flags, "sensitive")
+		}
+
+		model.Inputs = append(model.Inputs, readmeTemplateEnv{
+			Key:         key,
+			Description: readmeEnvDescription(opts.Inputs[i]),
+			Flags:       strings.Join(flags, ", "),
+			Value:       readmeTableCell(value),
+		})
+	}
+	for i, output := range step.Outputs {
+		key, _ := readmeEnvKeyValue(output)
+		model.Outputs = append(model.Outputs, readmeTemplateEnv{Key: key, Description: readmeEnvDescription(opts.Outputs[i])})
+	}
+
+	tmpl, err := template.New("readme").Parse(readmeSectionsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid README template: %w", err)
+	}
+
+	sections := map[string]string{}
+	for _, name := range readmeSectionNames {
+		var section bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&section, name, model); err != nil {
+			return nil, fmt.Errorf("failed to render the %s README section: %w", name, err)
+		}
+		sections[name] = section.String()
+	}
+	return sections, nil
+}
+
+func readmeEnvKeyValue(env map[string]interface{}) (string, string) {
+	for key, value := range env {
+		if key == "opts" {
+			continue
+		}
+		if value == nil {
+			return key, ""
+		}
+		return key, fmt.Sprint(value)
+	}
+	return "", ""
+}
+
+// readmeEnvDescription returns the most detailed description of the env, collapsed into a single table cell.
+func readmeEnvDescription(env readmeEnvModel) string {
+	description := env.Opts.Description
+	if description == "" {
+		description = env.Opts.Summary
+	}
+	if description == "" {
+		description = env.Opts.Title
+	}
+	return readmeTableCell(description)
+}
+
+// readmeTableCell collapses the value into a single line, so it fits in a Markdown table cell.
+func readmeTableCell(value string) string {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "\n", " ")
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// replaceREADMESections replaces the content of the README sections, and returns the names of the missing sections.
+func replaceREADMESections(readme string, sections map[string]string) (string, []string) {
+	var missing []string
+	for _, name := range readmeSectionNames {
+		start := "<summary>" + name + "</summary>\n"
+		startIndex := strings.Index(readme, start)
+		if startIndex == -1 {
+			missing = append(missing, name)
+			continue
+		}
+		contentIndex := startIndex + len(start)
+
+		endIndex := strings.Index(readme[contentIndex:], "</details>")
+		if endIndex == -1 {
+			missing = append(missing, name)
+			continue
+		}
+
+		readme = readme[:contentIndex] + "\n" + sections[name] + readme[contentIndex+endIndex:]
+	}
+
+	return readme, missing
+}
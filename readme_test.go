@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const readmeTestStepYML = `title: Step
+description: |
+  Does things.
+inputs:
+- mode: fast
+  opts:
+    title: Mode
+    summary: Speed of | the step
+    is_required: true
+- token:
+  opts:
+    title: Token
+    description: |-
+      API token,
+      keep it secret.
+    is_sensitive: true
+- workflow: |-
+    primary
+    deploy
+  opts:
+    title: Workflows
+outputs:
+- RESULT:
+  opts:
+    title: Result
+`
+
+func Test_renderREADMESections(t *testing.T) {
+	got, err := renderREADMESections([]byte(readmeTestStepYML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"Description": "Does things.\n",
+		"Inputs": "| Key | Description | Flags | Default |\n| --- | --- | --- | --- |\n" +
+			"| `mode` | Speed of \\| the step | required | `fast` |\n" +
+			"| `token` | API token, keep it secret. | sensitive |  |\n" +
+			"| `workflow` | Workflows |  | `primary deploy` |\n",
+		"Outputs": "| Environment Variable | Description |\n| --- | --- |\n" +
+			"| `RESULT` | Result |\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renderREADMESections() got = %#v, want %#v", got, want)
+	}
+}
+
+func Test_checkREADME(t *testing.T) {
+	sections, err := renderREADMESections([]byte(readmeTestStepYML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upToDate := "# Step\n\n<details>\n<summary>Description</summary>\n\n" + sections["Description"] + "</details>\n\n" +
+		"<details>\n<summary>Inputs</summary>\n\n" + sections["Inputs"] + "</details>\n\n" +
+		"<details>\n<summary>Outputs</summary>\n\n" + sections["Outputs"] + "</details>\n"
+
+	tests := []struct {
+		name     string
+		readme   string
+		wantRule string
+		wantLine int
+	}{
+		{"Up to date", upToDate, "", 0},
+		{"Outdated description", strings.Replace(upToDate, sections["Description"], "Old.\n", 1), "out-of-date", 6},
+		{"Missing section", "# Step\n", "missing-section", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := ioutil.WriteFile(filepath.Join(dir, "step.yml"), []byte(readmeTestStepYML), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, readmeFileName), []byte(tt.readme), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := checkREADME(dir)
+			if err != nil {
+				t.Fatalf("checkREADME() error = %v", err)
+			}
+			var gotRule string
+			var gotLine int
+			if len(got) > 0 {
+				gotRule, gotLine = got[0].Rule, got[0].Line
+			}
+			if gotRule == "out-of-date" && !strings.Contains(got[0].Details, "+++ README.md (expected)") {
+				t.Errorf("checkREADME() details = %q, want the unified diff", got[0].Details)
+			}
+			if gotRule != tt.wantRule || gotLine != tt.wantLine {
+				t.Errorf("checkREADME() got = %v, want rule %s at line %d", got, tt.wantRule, tt.wantLine)
+			}
+		})
+	}
+}
+
+func Test_renderREADMESections_empty(t *testing.T) {
+	got, err := renderREADMESections([]byte("description: Does things.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"Description": "Does things.\n",
+		"Inputs":      "There are no inputs defined in this step\n",
+		"Outputs":     "There are no outputs defined in this step\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renderREADMESections() got = %#v, want %#v", got, want)
+	}
+}
+
+func Test_replaceREADMESections(t *testing.T) {
+	readme := "<details>\n<summary>Inputs</summary>\n\nold\n</details>\n"
+	got, missing := replaceREADMESections(readme, map[string]string{"Inputs": "new\n"})
+
+	if want := "<details>\n<summary>Inputs</summary>\n\nnew\n</details>\n"; got != want {
+		t.Errorf("replaceREADMESections() got = %q, want %q", got, want)
+	}
+	if want := []string{"Description", "Outputs"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("replaceREADMESections() missing = %v, want %v", missing, want)
+	}
+}
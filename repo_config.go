@@ -0,0 +1,198 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed steps-check.schema.json
+var repoConfigSchema string
+
+const (
+	repoConfigFileName  = ".steps-check.yml"
+	repoConfigSchemaURL = "https://raw.githubusercontent.com/bitrise-steplib/steps-check/master/steps-check.schema.json"
+
+	// ruleSeverityOff ignores the findings of a rule.
+	ruleSeverityOff = "off"
+)
+
+// repoConfig is the policy of a step repo, stored in its .steps-check.yml:
+//
+//	checks:
+//	  yamlfmt:
+//	    enabled: true
+//	  yamllint:
+//	    rules:
+//	      comments: warning
+//	      braces: "off"
+//	exclude:
+//	- testdata/**
+//	e2e:
+//	  include: [test_*]
+//	  exclude: [test_slow_*]
+//	  timeout: 20m
+//	workflow_timeout: 30m
+//	baseline: .steps-check-baseline.json
+//
+// Step inputs, which are set, take precedence over the enabled checks.
+type repoConfig struct {
+	Checks          map[string]repoCheckConfig `yaml:"checks"`
+	Exclude         []string                   `yaml:"exclude"`
+	E2E             repoE2EConfig              `yaml:"e2e"`
+	WorkflowTimeout time.Duration              `yaml:"workflow_timeout"`
+	// Baseline is the path of the findings baseline file, relative to the repo directory.
+	Baseline string `yaml:"baseline"`
+}
+
+type repoCheckConfig struct {
+	Enabled *bool             `yaml:"enabled"`
+	Rules   map[string]string `yaml:"rules"`
+}
+
+type repoE2EConfig struct {
+	Include []string      `yaml:"include"`
+	Exclude []string      `yaml:"exclude"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// readRepoConfig reads the .steps-check.yml of the directory, an empty config is returned if it doesn't exist.
+func readRepoConfig(dir string) (repoConfig, error) {
+	pth := filepath.Join(dir, repoConfigFileName)
+	configBytes, err := ioutil.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return repoConfig{}, nil
+	} else if err != nil {
+		return repoConfig{}, err
+	}
+
+	config, err := parseRepoConfig(repoConfigFileName, configBytes)
+	if err != nil {
+		return repoConfig{}, fmt.Errorf("invalid %s: %w", pth, err)
+	}
+	return config, nil
+}
+
+// parseRepoConfig validates the config against the embedded schema, before decoding it.
+func parseRepoConfig(file string, configBytes []byte) (repoConfig, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(configBytes, &document); err != nil {
+		return repoConfig{}, err
+	}
+	if len(document.Content) == 0 {
+		return repoConfig{}, nil
+	}
+	root := document.Content[0]
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(repoConfigSchemaURL, strings.NewReader(repoConfigSchema)); err != nil {
+		return repoConfig{}, err
+	}
+	schema, err := compiler.Compile(repoConfigSchemaURL)
+	if err != nil {
+		return repoConfig{}, fmt.Errorf("invalid %s schema: %w", repoConfigFileName, err)
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if err := schema.Validate(yamlNodeToJSON(root)); errors.As(err, &validationErr) {
+		var messages []string
+		for _, leaf := range validationErrorLeaves(validationErr) {
+			node := schemaErrorNode(root, leaf)
+			messages = append(messages, fmt.Sprintf("%s:%d:%d: %s: %s", file, node.Line, node.Column, instancePath(leaf.InstanceLocation), leaf.Message))
+		}
+		return repoConfig{}, errors.New(strings.Join(messages, "\n"))
+	} else if err != nil {
+		return repoConfig{}, err
+	}
+
+	var config repoConfig
+	if err := root.Decode(&config); err != nil {
+		return repoConfig{}, err
+	}
+	return config, nil
+}
+
+// checkEnabled returns if the check runs. The step input's value is used if set,
+// otherwise the config's, falling back to the check's default.
+func (c repoConfig) checkEnabled(check string, input *bool, defaultEnabled bool) bool {
+	if input != nil {
+		return *input
+	}
+	if enabled := c.Checks[check].Enabled; enabled != nil {
+		return *enabled
+	}
+	return defaultEnabled
+}
+
+// apply drops the diagnostics of the excluded files and the disabled rules, and overrides the severities of the rules.
+func (c repoConfig) apply(diagnostics []diagnostic) []diagnostic {
+	var applied []diagnostic
+	for _, d := range diagnostics {
+		if isPathExcluded(c.Exclude, d.File) {
+			continue
+		}
+
+		switch severity := c.Checks[d.Check].Rules[d.Rule]; severity {
+		case "":
+		case ruleSeverityOff:
+			continue
+		default:
+			d.Severity = severity
+		}
+		applied = append(applied, d)
+	}
+	return applied
+}
+
+// isPathExcluded returns if the slash separated relative file path, or any of its parent directories, is excluded.
+func isPathExcluded(patterns []string, rel string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	rel = filepath.ToSlash(rel)
+	if matchesExcludePattern(patterns, rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if matchesExcludePattern(patterns, dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesExcludePattern matches the slash separated relative path against the doublestar style exclude patterns.
+// Patterns ending with /** exclude a directory, ** only matches at the start of a pattern.
+func matchesExcludePattern(patterns []string, rel string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if dir := strings.TrimSuffix(pattern, "/**"); dir != pattern {
+			if isDir {
+				if matched, _ := filepath.Match(dir, rel); matched {
+					return true
+				}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "**/") {
+			if matched, _ := filepath.Match(strings.TrimPrefix(pattern, "**/"), rel[strings.LastIndex(rel, "/")+1:]); matched {
+				return true
+			}
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseRepoConfig(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name    string
+		config  string
+		want    repoConfig
+		wantErr string
+	}{
+		{
+			name:   "Empty",
+			config: "",
+			want:   repoConfig{},
+		},
+		{
+			name: "Full",
+			config: `checks:
+  yamlfmt:
+    enabled: true
+  yamllint:
+    rules:
+      comments: warning
+      braces: "off"
+exclude:
+- testdata/**
+e2e:
+  include: [test_*]
+  exclude: [test_slow_*]
+  timeout: 20m
+workflow_timeout: 1h30m
+`,
+			want: repoConfig{
+				Checks: map[string]repoCheckConfig{
+					checkYAMLFmt:  {Enabled: &enabled},
+					checkYAMLLint: {Rules: map[string]string{"comments": severityWarning, "braces": ruleSeverityOff}},
+				},
+				Exclude:         []string{"testdata/**"},
+				E2E:             repoE2EConfig{Include: []string{"test_*"}, Exclude: []string{"test_slow_*"}, Timeout: 20 * time.Minute},
+				WorkflowTimeout: 90 * time.Minute,
+			},
+		},
+		{
+			name:    "Unknown check",
+			config:  "checks:\n  shellcheck:\n    enabled: true\n",
+			wantErr: ".steps-check.yml:2:3: #/checks/shellcheck: value must be one of",
+		},
+		{
+			name:    "Invalid severity",
+			config:  "checks:\n  audit:\n    rules:\n      url: info\n",
+			wantErr: ".steps-check.yml:4:12: #/checks/audit/rules/url:",
+		},
+		{
+			name:    "Invalid duration",
+			config:  "e2e:\n  timeout: 20 minutes\n",
+			wantErr: ".steps-check.yml:2:12: #/e2e/timeout: does not match pattern",
+		},
+		{
+			name:    "Unknown field",
+			config:  "skip_go_checks: true\n",
+			wantErr: ".steps-check.yml:1:1: #: additionalProperties 'skip_go_checks' not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRepoConfig(repoConfigFileName, []byte(tt.config))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseRepoConfig() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRepoConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRepoConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readRepoConfig_missing(t *testing.T) {
+	got, err := readRepoConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("readRepoConfig() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, repoConfig{}) {
+		t.Errorf("readRepoConfig() = %#v, want an empty config", got)
+	}
+}
+
+func Test_readRepoConfig_invalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, repoConfigFileName), []byte("exclude: vendor\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readRepoConfig(dir)
+	if err == nil || !strings.Contains(err.Error(), "#/exclude: expected array, but got string") {
+		t.Errorf("readRepoConfig() error = %v", err)
+	}
+}
+
+func Test_enabledChecks(t *testing.T) {
+	yes, no := true, false
+	repoCfg := repoConfig{Checks: map[string]repoCheckConfig{
+		checkYAMLFmt:      {Enabled: &yes},
+		checkGolangciLint: {Enabled: &no},
+		checkGoTest:       {Enabled: &no},
+	}}
+
+	tests := []struct {
+		name    string
+		config  Config
+		repoCfg repoConfig
+		want    map[string]bool
+	}{
+		{
+			name: "Defaults",
+			want: map[string]bool{
+				checkYAMLLint: true, checkYAMLFmt: false, checkAudit: true, checkSchema: true,
+				checkREADMEDiff: true, checkGolangciLint: true, checkGoTest: true,
+			},
+		},
+		{
+			name:    "Repo config",
+			repoCfg: repoCfg,
+			want: map[string]bool{
+				checkYAMLLint: true, checkYAMLFmt: true, checkAudit: true, checkSchema: true,
+				checkREADMEDiff: true, checkGolangciLint: false, checkGoTest: false,
+			},
+		},
+		{
+			name:    "Inputs override the repo config",
+			config:  Config{CheckYAMLFormatting: &no, SkipGoChecks: &no, SkipStepYMLValidation: &yes},
+			repoCfg: repoCfg,
+			want: map[string]bool{
+				checkYAMLLint: true, checkYAMLFmt: false, checkAudit: false, checkSchema: false,
+				checkREADMEDiff: false, checkGolangciLint: true, checkGoTest: false,
+			},
+		},
+		{
+			name:   "Scheduled batch CI run",
+			config: Config{IsScheduledCIRun: true},
+			want: map[string]bool{
+				checkYAMLLint: true, checkYAMLFmt: false, checkAudit: true, checkSchema: true,
+				checkREADMEDiff: false, checkGolangciLint: true, checkGoTest: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enabledChecks(tt.config, tt.repoCfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enabledChecks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_repoConfig_apply(t *testing.T) {
+	repoCfg := repoConfig{
+		Checks: map[string]repoCheckConfig{
+			checkYAMLLint: {Rules: map[string]string{"comments": severityWarning, "braces": ruleSeverityOff}},
+		},
+		Exclude: []string{"testdata/**", "**/*.gen.yml"},
+	}
+	diagnostics := []diagnostic{
+		{Check: checkYAMLLint, Rule: "comments", File: "bitrise.yml", Severity: severityError},
+		{Check: checkYAMLLint, Rule: "braces", File: "bitrise.yml", Severity: severityError},
+		{Check: checkYAMLLint, Rule: "indentation", File: "bitrise.yml", Severity: severityError},
+		{Check: checkAudit, Rule: "comments", File: "step.yml", Severity: severityError},
+		{Check: checkYAMLLint, Rule: "indentation", File: "testdata/nested/step.yml", Severity: severityError},
+		{Check: checkYAMLLint, Rule: "indentation", File: "e2e/config.gen.yml", Severity: severityError},
+	}
+
+	want := []diagnostic{
+		{Check: checkYAMLLint, Rule: "comments", File: "bitrise.yml", Severity: severityWarning},
+		{Check: checkYAMLLint, Rule: "indentation", File: "bitrise.yml", Severity: severityError},
+		{Check: checkAudit, Rule: "comments", File: "step.yml", Severity: severityError},
+	}
+	if got := repoCfg.apply(diagnostics); !reflect.DeepEqual(got, want) {
+		t.Errorf("apply() = %#v, want %#v", got, want)
+	}
+}
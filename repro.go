@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	reproTmpDirVar       = "tmp_dir"
+	reproHeredocMarker   = "STEPS_CHECK_EOF"
+	reproRedactedValue   = "<redacted>"
+	reproSecretsEnvKey   = "BITRISE_SECRETS_INVENTORY"
+	reproScriptNameRegex = `[^A-Za-z0-9_.-]+`
+)
+
+// reproScript describes how to reproduce a failed check locally.
+type reproScript struct {
+	Name string
+	Dir  string
+	// TmpDir is set if Dir is temporary, then an empty directory is created for the command under the script's
+	// temporary directory.
+	TmpDir bool
+	// Files are embedded into the script, and recreated in a temporary directory before running the command.
+	Files   []reproFile
+	Envs    []reproEnv
+	Command []string
+}
+
+type reproFile struct {
+	// Path is the original location of the file, its occurrences in Envs and Command point to the recreated file.
+	Path    string
+	Content string
+}
+
+type reproEnv struct {
+	Key    string
+	Value  string
+	Secret bool
+}
+
+// reproError is a failure, which can be reproduced by running a script.
+type reproError struct {
+	err    error
+	script reproScript
+}
+
+func (e *reproError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reproError) Unwrap() error {
+	return e.err
+}
+
+func withRepro(err error, script reproScript) error {
+	if err == nil {
+		return nil
+	}
+	return &reproError{err: err, script: script}
+}
+
+// updateRepro applies fn to the reproduction script of the error, if it has one.
+func updateRepro(err error, fn func(script *reproScript)) {
+	var reproErr *reproError
+	if errors.As(err, &reproErr) {
+		fn(&reproErr.script)
+	}
+}
+
+// replaceFile replaces the content of the embedded file of the given path.
+func (r *reproScript) replaceFile(pth, content string) {
+	for i, file := range r.Files {
+		if file.Path == pth {
+			r.Files[i].Content = content
+		}
+	}
+}
+
+// e2eReproScript describes the `bitrise run` invocation of an E2E workflow. Configs outside of the step directory
+// are temporary, so they are embedded. Temporary secrets inventories are replaced by a placeholder.
+func e2eReproScript(name, workDir, configPath, secretsPath, workflow string) (reproScript, error) {
+	script := reproScript{
+		Name:    name,
+		Dir:     workDir,
+		Command: []string{"bitrise", "run", "--config", configPath},
+	}
+
+	if !isInDir(configPath, workDir) {
+		content, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return reproScript{}, err
+		}
+		script.Files = append(script.Files, reproFile{Path: configPath, Content: string(content)})
+	}
+
+	if secretsPath != "" {
+		if isInDir(secretsPath, workDir) {
+			script.Command = append(script.Command, "--inventory", secretsPath)
+		} else {
+			script.Envs = append(script.Envs, reproEnv{Key: reproSecretsEnvKey, Secret: true})
+			script.Command = append(script.Command, "--inventory", "$"+reproSecretsEnvKey)
+		}
+	}
+	script.Command = append(script.Command, workflow)
+
+	return script, nil
+}
+
+// nativeCheckReproScript describes the steps-check CLI invocation of a native check, named like the check.
+func nativeCheckReproScript(workDir, check string) reproScript {
+	return reproScript{
+		Name:    check,
+		Dir:     workDir,
+		Command: []string{"steps-check", check},
+	}
+}
+
+func isInDir(pth, dir string) bool {
+	rel, err := filepath.Rel(dir, pth)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+func (r reproScript) render() string {
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	fmt.Fprintf(&b, "# Reproduces the failed '%s' check\n", r.Name)
+	b.WriteString("set -ex\n\n")
+
+	if len(r.Files) > 0 || r.TmpDir {
+		fmt.Fprintf(&b, "%s=$(mktemp -d)\n", reproTmpDirVar)
+	}
+	if len(r.Files) > 0 {
+		for _, file := range r.Files {
+			fmt.Fprintf(&b, "cat > %s <<'%s'\n%s", r.expand(file.Path), reproHeredocMarker, file.Content)
+			if !strings.HasSuffix(file.Content, "\n") {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "%s\n", reproHeredocMarker)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, env := range r.Envs {
+		if env.Secret {
+			fmt.Fprintf(&b, "export %s=%s # set the value before running\n", env.Key, shellQuote(reproRedactedValue))
+		} else {
+			fmt.Fprintf(&b, "export %s=%s\n", env.Key, r.expand(env.Value))
+		}
+	}
+
+	if r.TmpDir {
+		dir := fmt.Sprintf(`"$%s/%s"`, reproTmpDirVar, filepath.Base(r.Dir))
+		fmt.Fprintf(&b, "\nmkdir -p %s\ncd %s\n", dir, dir)
+	} else {
+		fmt.Fprintf(&b, "\ncd %s\n", shellQuote(r.Dir))
+	}
+	var args []string
+	for _, arg := range r.Command {
+		args = append(args, r.expand(arg))
+	}
+	b.WriteString(strings.Join(args, " ") + "\n")
+
+	return b.String()
+}
+
+// expand quotes the value for the shell, pointing embedded file paths to their recreated copies.
+func (r reproScript) expand(value string) string {
+	for i, file := range r.Files {
+		if value == file.Path {
+			return fmt.Sprintf(`"$%s/%d-%s"`, reproTmpDirVar, i, filepath.Base(file.Path))
+		}
+	}
+	if strings.HasPrefix(value, "$") && !strings.ContainsAny(value, " '\"") {
+		return `"` + value + `"`
+	}
+	return shellQuote(value)
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// writeReproForError writes the reproduction script of a failed check, if the error has one.
+// It returns the path of the script, or an empty string if no script was written.
+func writeReproForError(dir, name string, err error) string {
+	var reproErr *reproError
+	if dir == "" || !errors.As(err, &reproErr) {
+		return ""
+	}
+
+	script := reproErr.script
+	script.Name = name
+	pth, err := writeReproScript(dir, script)
+	if err != nil {
+		log.Warnf("Failed to write reproduction script: %s", err)
+		return ""
+	}
+	return pth
+}
+
+// writeReproScript writes the script into dir, and returns its path.
+func writeReproScript(dir string, script reproScript) (string, error) {
+	name := regexp.MustCompile(reproScriptNameRegex).ReplaceAllString(script.Name, "_")
+	pth := filepath.Join(dir, fmt.Sprintf("repro-%s.sh", name))
+	if err := ioutil.WriteFile(pth, []byte(script.render()), 0755); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_reproScript_render(t *testing.T) {
+	script := reproScript{
+		Name: "lint",
+		Dir:  "/step",
+		Files: []reproFile{
+			{Path: "/tmp/123/bitrise.yml", Content: "workflows:\n  lint: {}\n"},
+		},
+		Envs: []reproEnv{
+			{Key: "STEP_DIR", Value: "/step"},
+			{Key: "BITRISE_SECRETS_INVENTORY", Value: "/tmp/secrets.yml", Secret: true},
+		},
+		Command: []string{"bitrise", "run", "lint", "--config", "/tmp/123/bitrise.yml", "--inventory", "$BITRISE_SECRETS_INVENTORY"},
+	}
+
+	want := `#!/usr/bin/env bash
+# Reproduces the failed 'lint' check
+set -ex
+
+tmp_dir=$(mktemp -d)
+cat > "$tmp_dir/0-bitrise.yml" <<'STEPS_CHECK_EOF'
+workflows:
+  lint: {}
+STEPS_CHECK_EOF
+
+export STEP_DIR='/step'
+export BITRISE_SECRETS_INVENTORY='<redacted>' # set the value before running
+
+cd '/step'
+'bitrise' 'run' 'lint' '--config' "$tmp_dir/0-bitrise.yml" '--inventory' "$BITRISE_SECRETS_INVENTORY"
+`
+	if got := script.render(); got != want {
+		t.Errorf("render() got = %s, want %s", got, want)
+	}
+}
+
+func Test_e2eReproScript(t *testing.T) {
+	script, err := e2eReproScript("test_a", "/step", "/step/e2e/bitrise.yml", "/tmp/e2e-secrets/.bitrise.secrets.yml", "test_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script.Files) != 0 {
+		t.Errorf("e2eReproScript() config in the step directory should not be embedded")
+	}
+	want := []string{"bitrise", "run", "--config", "/step/e2e/bitrise.yml", "--inventory", "$BITRISE_SECRETS_INVENTORY", "test_a"}
+	if fmt.Sprint(script.Command) != fmt.Sprint(want) {
+		t.Errorf("e2eReproScript() got command = %v, want %v", script.Command, want)
+	}
+	if len(script.Envs) != 1 || !script.Envs[0].Secret {
+		t.Errorf("e2eReproScript() temporary secrets inventory should be redacted, got envs = %v", script.Envs)
+	}
+
+	wrapped := fmt.Errorf("test failed: %w", withRepro(errors.New("exit status 1"), script))
+	dir := t.TempDir()
+	if pth := writeReproForError(dir, "test_a", wrapped); pth != dir+"/repro-test_a.sh" {
+		t.Errorf("writeReproForError() got = %s", pth)
+	}
+	if pth := writeReproForError(dir, "test_a", errors.New("exit status 1")); pth != "" {
+		t.Errorf("writeReproForError() got = %s, want no script for errors without reproduction", pth)
+	}
+}
+
+func Test_nativeCheckReproScript(t *testing.T) {
+	want := `#!/usr/bin/env bash
+# Reproduces the failed 'yamllint' check
+set -ex
+
+
+cd '/step'
+'steps-check' 'yamllint'
+`
+	if got := nativeCheckReproScript("/step", checkYAMLLint).render(); got != want {
+		t.Errorf("render() got = %s, want %s", got, want)
+	}
+}
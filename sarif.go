@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifFileName = "steps-check.sarif"
+	// sarifSrcRoot is the base of the artifact locations, the step repo's root directory.
+	sarifSrcRoot = "%SRCROOT%"
+
+	stepsCheckInformationURI = "https://github.com/bitrise-steplib/steps-check"
+)
+
+// sarifLog is the root of a SARIF 2.1.0 file, with the subset of the format steps-check reports.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifRuleConfiguration `json:"defaultConfiguration"`
+	Properties           sarifRuleProperties    `json:"properties"`
+}
+
+type sarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifRuleProperties struct {
+	Tags []string `json:"tags"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	// BaselineState is set if the repo has a findings baseline: unchanged for the baselined findings, new otherwise.
+	BaselineState string `json:"baselineState,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifRuleID is the ID of the diagnostic's rule, unique across the checks.
+func sarifRuleID(d diagnostic) string {
+	if d.Rule == "" {
+		return d.Check
+	}
+	return d.Check + "/" + d.Rule
+}
+
+// sarifRuleMetadata returns the description and the documentation URL of a rule of a check.
+func sarifRuleMetadata(check, rule string) (string, string) {
+	switch check {
+	case checkAudit:
+		return stepAuditRules[rule], stepsCheckInformationURI
+	case checkSchema:
+		return fmt.Sprintf("step.yml must satisfy the '%s' keyword of the step.yml JSON schema", rule), stepSchemaURL
+	case checkREADMEDiff:
+		return readmeRules[rule], stepsCheckInformationURI
+	case checkYAMLLint:
+		switch rule {
+		case "syntax":
+			return "YAML files must be syntactically valid", "https://yamllint.readthedocs.io/en/stable/rules.html"
+		case "unsupported-rule":
+			return "The configured yamllint rules must be supported", "https://yamllint.readthedocs.io/en/stable/rules.html"
+		}
+		return yamllintRules[rule].description, "https://yamllint.readthedocs.io/en/stable/rules.html#module-yamllint.rules." + strings.ReplaceAll(rule, "-", "_")
+	case checkYAMLFmt:
+		return yamlfmtRuleDescription, "https://github.com/google/yamlfmt"
+	case checkGolangciLint:
+		return fmt.Sprintf("Issues reported by the %s linter", rule), "https://golangci-lint.run/usage/linters/#" + rule
+	case checkGoTest:
+		return fmt.Sprintf("The %s test must pass", rule), ""
+	case checkBaseline:
+		return "Findings baseline entries should match a current finding", stepsCheckInformationURI
+	default:
+		return "", ""
+	}
+}
+
+// newSARIFLog converts the diagnostics into a SARIF log with a single run.
+// The file paths of the diagnostics need to be relative to the step repo's root directory,
+// which is recorded as the base of the artifact locations if not empty.
+// The baseline state of the results is only set, if the repo has a findings baseline.
+func newSARIFLog(repoDir string, diagnostics []diagnostic, hasBaseline bool) sarifLog {
+	sorted := append([]diagnostic(nil), diagnostics...)
+	sortDiagnostics(sorted)
+
+	ruleIndexes := map[string]int{}
+	rules := []sarifRule{}
+	for _, d := range sorted {
+		id := sarifRuleID(d)
+		if _, ok := ruleIndexes[id]; ok {
+			continue
+		}
+
+		description, helpURI := sarifRuleMetadata(d.Check, d.Rule)
+		if description == "" {
+			description = id
+		}
+		rules = append(rules, sarifRule{
+			ID:                   id,
+			Name:                 d.Rule,
+			ShortDescription:     sarifMessage{Text: description},
+			HelpURI:              helpURI,
+			DefaultConfiguration: sarifRuleConfiguration{Level: sarifLevel(d.Severity)},
+			Properties:           sarifRuleProperties{Tags: []string{d.Check}},
+		})
+		ruleIndexes[id] = -1
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	for i, rule := range rules {
+		ruleIndexes[rule.ID] = i
+	}
+
+	results := []sarifResult{}
+	for _, d := range sorted {
+		message := d.Message
+		if d.Fix != "" {
+			message += fmt.Sprintf(" (fix: %s)", d.Fix)
+		}
+
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File), URIBaseID: sarifSrcRoot},
+		}
+		if d.Line > 0 {
+			location.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+		}
+
+		id := sarifRuleID(d)
+		result := sarifResult{
+			RuleID:    id,
+			RuleIndex: ruleIndexes[id],
+			Level:     sarifLevel(d.Severity),
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		}
+		if hasBaseline {
+			result.BaselineState = "new"
+			if d.Baselined {
+				result.BaselineState = "unchanged"
+			}
+		}
+		results = append(results, result)
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "steps-check",
+			InformationURI: stepsCheckInformationURI,
+			Rules:          rules,
+		}},
+		Results: results,
+	}
+	if repoDir != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{sarifSrcRoot: {URI: fileURI(repoDir)}}
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLevel(severity string) string {
+	if severity == severityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// writeSARIF writes the diagnostics of the repo into a SARIF file in the directory, and returns its path.
+func writeSARIF(dir, repoDir string, diagnostics []diagnostic, hasBaseline bool) (string, error) {
+	content, err := json.MarshalIndent(newSARIFLog(repoDir, diagnostics, hasBaseline), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	pth := filepath.Join(dir, sarifFileName)
+	if err := ioutil.WriteFile(pth, append(content, '\n'), 0600); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
+
+// fileURI returns the file URI of the directory, with a trailing slash as SARIF requires for base URIs.
+func fileURI(dir string) string {
+	uri := filepath.ToSlash(dir)
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+	return "file://" + uri
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_newSARIFLog(t *testing.T) {
+	diagnostics := []diagnostic{
+		{Check: checkYAMLLint, Rule: "trailing-spaces", File: "step.yml", Line: 4, Column: 12, Severity: severityError, Message: "trailing spaces"},
+		{Check: checkAudit, Rule: "url", File: "step.yml", Line: 3, Column: 10, Severity: severityError, Message: "invalid URL"},
+		{Check: checkREADMEDiff, Rule: "out-of-date", File: "README.md", Line: 7, Severity: severityWarning, Message: "out of date", Fix: "run steps-check readme -w"},
+		{Check: checkYAMLFmt, Rule: yamlfmtRule, File: "bitrise.yml", Severity: severityError, Message: "not formatted"},
+		{Check: checkAudit, Rule: "url", File: "step.yml", Line: 5, Column: 10, Severity: severityError, Message: "invalid URL"},
+	}
+
+	got := newSARIFLog("/bitrise/src", diagnostics, false)
+
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("newSARIFLog() version = %s, runs = %d", got.Version, len(got.Runs))
+	}
+	run := got.Runs[0]
+	if base := run.OriginalURIBaseIDs[sarifSrcRoot].URI; base != "file:///bitrise/src/" {
+		t.Errorf("newSARIFLog() base URI = %s", base)
+	}
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	wantRuleIDs := []string{"audit/url", "readme/out-of-date", "yamlfmt/formatting", "yamllint/trailing-spaces"}
+	if !reflect.DeepEqual(ruleIDs, wantRuleIDs) {
+		t.Errorf("newSARIFLog() rules = %v, want %v", ruleIDs, wantRuleIDs)
+	}
+	if rule := run.Tool.Driver.Rules[3]; rule.ShortDescription.Text != "Lines must not end with spaces" ||
+		rule.HelpURI != "https://yamllint.readthedocs.io/en/stable/rules.html#module-yamllint.rules.trailing_spaces" {
+		t.Errorf("newSARIFLog() rule metadata = %#v", rule)
+	}
+
+	wantResults := []sarifResult{
+		{
+			RuleID: "readme/out-of-date", RuleIndex: 1, Level: "warning",
+			Message: sarifMessage{Text: "out of date (fix: run steps-check readme -w)"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "README.md", URIBaseID: sarifSrcRoot},
+				Region:           &sarifRegion{StartLine: 7},
+			}}},
+		},
+		{
+			RuleID: "yamlfmt/formatting", RuleIndex: 2, Level: "error",
+			Message: sarifMessage{Text: "not formatted"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "bitrise.yml", URIBaseID: sarifSrcRoot},
+			}}},
+		},
+		{
+			RuleID: "audit/url", RuleIndex: 0, Level: "error",
+			Message: sarifMessage{Text: "invalid URL"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "step.yml", URIBaseID: sarifSrcRoot},
+				Region:           &sarifRegion{StartLine: 3, StartColumn: 10},
+			}}},
+		},
+		{
+			RuleID: "yamllint/trailing-spaces", RuleIndex: 3, Level: "error",
+			Message: sarifMessage{Text: "trailing spaces"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "step.yml", URIBaseID: sarifSrcRoot},
+				Region:           &sarifRegion{StartLine: 4, StartColumn: 12},
+			}}},
+		},
+		{
+			RuleID: "audit/url", RuleIndex: 0, Level: "error",
+			Message: sarifMessage{Text: "invalid URL"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "step.yml", URIBaseID: sarifSrcRoot},
+				Region:           &sarifRegion{StartLine: 5, StartColumn: 10},
+			}}},
+		},
+	}
+	if !reflect.DeepEqual(run.Results, wantResults) {
+		t.Errorf("newSARIFLog() results = %#v, want %#v", run.Results, wantResults)
+	}
+}
+
+func Test_writeSARIF(t *testing.T) {
+	dir := t.TempDir()
+
+	pth, err := writeSARIF(dir, "", nil, false)
+	if err != nil {
+		t.Fatalf("writeSARIF() error = %v", err)
+	}
+	if pth != filepath.Join(dir, sarifFileName) {
+		t.Errorf("writeSARIF() path = %s", pth)
+	}
+
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("invalid SARIF file: %v", err)
+	}
+	runs := got["runs"].([]interface{})
+	run := runs[0].(map[string]interface{})
+	if results, ok := run["results"].([]interface{}); !ok || len(results) != 0 {
+		t.Errorf("writeSARIF() results = %v, want an empty list", run["results"])
+	}
+	if _, ok := run["originalUriBaseIds"]; ok {
+		t.Errorf("writeSARIF() originalUriBaseIds is set without a repo dir")
+	}
+}
+
+func Test_fileURI(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"/bitrise/src", "file:///bitrise/src/"},
+		{"/bitrise/src/", "file:///bitrise/src/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := fileURI(tt.dir); got != tt.want {
+				t.Errorf("fileURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
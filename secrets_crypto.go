@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	encryptedSecretsFileName = ".bitrise.secrets.enc.yml"
+	encryptedSecretsFormat   = "steps-check-secrets/v1"
+	secretsKeyEnvKey         = "STEPS_CHECK_SECRETS_KEY"
+	secretsKeySize           = 32
+)
+
+// encryptedSecretsModel is an AES-256-GCM encrypted secrets inventory, which can be committed to the step repo.
+type encryptedSecretsModel struct {
+	Format string `yaml:"format"`
+	// Data is the base64 encoded nonce and ciphertext.
+	Data string `yaml:"data"`
+}
+
+func lookupEncryptedSecrets(workDir string) (string, error) {
+	secretLookupPaths := []string{
+		filepath.Join(workDir, "e2e", encryptedSecretsFileName),
+		filepath.Join(workDir, encryptedSecretsFileName),
+	}
+	for _, secretPath := range secretLookupPaths {
+		if exists, err := pathutil.IsPathExists(secretPath); err != nil {
+			return "", err
+		} else if exists {
+			return secretPath, nil
+		}
+	}
+
+	return "", nil
+}
+
+func generateSecretsKey() (string, error) {
+	key := make([]byte, secretsKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func newSecretsCipher(encodedKey string) (cipher.AEAD, error) {
+	if encodedKey == "" {
+		return nil, fmt.Errorf("no secrets key set, set it in the %s env var", secretsKeyEnvKey)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %w", err)
+	}
+	if len(key) != secretsKeySize {
+		return nil, fmt.Errorf("invalid secrets key: expected %d bytes, got %d", secretsKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptSecrets(plaintext []byte, encodedKey string) ([]byte, error) {
+	aead, err := newSecretsCipher(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(encryptedSecretsFormat))
+
+	return yaml.Marshal(encryptedSecretsModel{
+		Format: encryptedSecretsFormat,
+		Data:   base64.StdEncoding.EncodeToString(sealed),
+	})
+}
+
+func decryptSecrets(encrypted []byte, encodedKey string) ([]byte, error) {
+	var model encryptedSecretsModel
+	if err := yaml.UnmarshalStrict(encrypted, &model); err != nil {
+		return nil, err
+	}
+	if model.Format != encryptedSecretsFormat {
+		return nil, fmt.Errorf("unsupported encrypted secrets format: %s", model.Format)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(model.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newSecretsCipher(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted secrets are truncated")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(encryptedSecretsFormat))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets, is %s the right key? %w", secretsKeyEnvKey, err)
+	}
+	return plaintext, nil
+}
+
+// decryptSecretsToTemp decrypts the encrypted inventory into a private temporary directory.
+func decryptSecretsToTemp(encryptedPath, encodedKey string) (string, func(), error) {
+	encrypted, err := ioutil.ReadFile(encryptedPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext, err := decryptSecrets(encrypted, encodedKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", encryptedPath, err)
+	}
+
+	return writeSecretsToTemp(plaintext)
+}
+
+// writeSecretsToTemp writes the secrets inventory into a private temporary directory.
+// The returned cleanup function removes it. It is also removed if the process is interrupted,
+// then the signal is raised again.
+func writeSecretsToTemp(inventory []byte) (string, func(), error) {
+	// ioutil.TempDir creates the directory with 0700 permissions
+	tmpDir, err := ioutil.TempDir("", "e2e-secrets")
+	if err != nil {
+		return "", nil, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	cleanup := func() {
+		signal.Stop(signals)
+		close(done)
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove temporary secrets (%s): %s", tmpDir, err)
+		}
+	}
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			if err := os.RemoveAll(tmpDir); err != nil {
+				log.Warnf("Failed to remove temporary secrets (%s): %s", tmpDir, err)
+			}
+			log.Errorf("Interrupted by %s", sig)
+			// Re-raise the signal with its default handling, so the process terminates the way it was asked to
+			signal.Stop(signals)
+			if err := raiseSignal(sig); err != nil {
+				log.Warnf("Failed to re-raise %s: %s", sig, err)
+			}
+		case <-done:
+		}
+	}()
+
+	secretsPath := filepath.Join(tmpDir, defaultBitriseSecretsName)
+	if err := ioutil.WriteFile(secretsPath, inventory, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return secretsPath, cleanup, nil
+}
+
+func raiseSignal(sig os.Signal) error {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
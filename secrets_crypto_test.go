@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_encryptSecrets_decryptSecrets(t *testing.T) {
+	key, err := generateSecretsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := generateSecretsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("envs:\n- API_TOKEN: secret\n")
+	encrypted, err := encryptSecrets(plaintext, key)
+	if err != nil {
+		t.Fatalf("encryptSecrets() error = %v", err)
+	}
+
+	decrypted, err := decryptSecrets(encrypted, key)
+	if err != nil {
+		t.Fatalf("decryptSecrets() error = %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Errorf("decryptSecrets() got = %s, want %s", decrypted, plaintext)
+	}
+
+	if _, err := decryptSecrets(encrypted, otherKey); err == nil {
+		t.Errorf("decryptSecrets() expected error for wrong key")
+	}
+	if _, err := decryptSecrets(encrypted, ""); err == nil {
+		t.Errorf("decryptSecrets() expected error for missing key")
+	}
+	if _, err := encryptSecrets(plaintext, "c2hvcnQ="); err == nil {
+		t.Errorf("encryptSecrets() expected error for short key")
+	}
+}
+
+func Test_decryptSecretsToTemp(t *testing.T) {
+	key, err := generateSecretsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptSecrets([]byte("envs: []\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPath := filepath.Join(t.TempDir(), encryptedSecretsFileName)
+	if err := ioutil.WriteFile(encryptedPath, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	secretsPath, cleanup, err := decryptSecretsToTemp(encryptedPath, key)
+	if err != nil {
+		t.Fatalf("decryptSecretsToTemp() error = %v", err)
+	}
+
+	info, err := os.Stat(secretsPath)
+	if err != nil {
+		t.Fatalf("decrypted secrets not found: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("decrypted secrets permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	cleanup()
+	if _, err := os.Stat(filepath.Dir(secretsPath)); !os.IsNotExist(err) {
+		t.Errorf("decrypted secrets were not removed: %v", err)
+	}
+}
+
+func Test_writeSecretsToTemp_signal(t *testing.T) {
+	if os.Getenv("STEPS_CHECK_TEST_SIGNAL") == "1" {
+		secretsPath, _, err := writeSecretsToTemp([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(secretsPath)
+		if err := raiseSignal(syscall.SIGTERM); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Second)
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can not be sent to the process on Windows")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_writeSecretsToTemp_signal$")
+	cmd.Env = append(os.Environ(), "STEPS_CHECK_TEST_SIGNAL=1")
+	out, err := cmd.Output()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected the process to be terminated, got error = %v, output: %s", err, out)
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("expected the process to be terminated by SIGTERM, got: %s", exitErr)
+	}
+
+	secretsPath := strings.TrimSpace(string(out))
+	if secretsPath == "" {
+		t.Fatalf("secrets path not printed")
+	}
+	if _, err := os.Stat(filepath.Dir(secretsPath)); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary secrets to be removed, got error = %v", err)
+	}
+}
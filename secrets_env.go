@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// secretsInventoryFromEnv builds a secrets inventory from the env vars starting with prefix.
+// The prefix is trimmed from the keys, so E2E_SECRET_API_TOKEN becomes API_TOKEN with the E2E_SECRET_ prefix.
+// Every required key (without the prefix) needs to be present with a non-empty value.
+func secretsInventoryFromEnv(environ []string, prefix string, required []string) ([]byte, []string, error) {
+	values := map[string]string{}
+	for _, env := range environ {
+		split := strings.SplitN(env, "=", 2)
+		if len(split) != 2 || !strings.HasPrefix(split[0], prefix) {
+			continue
+		}
+		if key := strings.TrimPrefix(split[0], prefix); key != "" {
+			values[key] = split[1]
+		}
+	}
+
+	var missing []string
+	for _, key := range required {
+		if values[key] == "" {
+			missing = append(missing, prefix+key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("required secrets are not set: %s", strings.Join(missing, ", "))
+	}
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var envs []interface{}
+	for _, key := range keys {
+		envs = append(envs, yaml.MapSlice{
+			{Key: key, Value: values[key]},
+			// Secret values are used as is, a $ in them should not be expanded
+			{Key: "opts", Value: yaml.MapSlice{{Key: "is_expand", Value: false}}},
+		})
+	}
+
+	inventory, err := yaml.Marshal(yaml.MapSlice{{Key: "envs", Value: envs}})
+	if err != nil {
+		return nil, nil, err
+	}
+	return inventory, keys, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_secretsInventoryFromEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"E2E_SECRET_API_TOKEN=token",
+		"E2E_SECRET_PASSWORD=pa$$word",
+		"E2E_SECRET_EMPTY=",
+		"E2E_SECRET_=no key",
+	}
+
+	tests := []struct {
+		name     string
+		required []string
+		want     string
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			"Inventory from prefixed env vars",
+			[]string{"API_TOKEN"},
+			`envs:
+- API_TOKEN: token
+  opts:
+    is_expand: false
+- EMPTY: ""
+  opts:
+    is_expand: false
+- PASSWORD: pa$$word
+  opts:
+    is_expand: false
+`,
+			[]string{"API_TOKEN", "EMPTY", "PASSWORD"},
+			false,
+		},
+		{
+			"Missing and empty required secrets",
+			[]string{"API_TOKEN", "EMPTY", "CERTIFICATE"},
+			"",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, keys, err := secretsInventoryFromEnv(environ, "E2E_SECRET_", tt.required)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("secretsInventoryFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("secretsInventoryFromEnv() got = %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("secretsInventoryFromEnv() got keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// validateSecretsInventory checks that the inventory has the format `bitrise run --inventory` expects:
+//
+//	envs:
+//	- API_TOKEN: value
+//	  opts:
+//	    is_expand: false
+//
+// Structural problems are returned as errors. Empty values and keys not referenced by any of the
+// given E2E config contents are returned as warnings.
+func validateSecretsInventory(inventory []byte, references []string) ([]string, error) {
+	var model yaml.MapSlice
+	if err := yaml.Unmarshal(inventory, &model); err != nil {
+		return nil, err
+	}
+
+	var envs []interface{}
+	hasEnvs := false
+	for _, item := range model {
+		if item.Key != "envs" {
+			return nil, fmt.Errorf("unexpected top-level key: %v, only envs is allowed", item.Key)
+		}
+		// Decoding into a MapSlice keeps duplicate keys, the last of which would win
+		if hasEnvs {
+			return nil, fmt.Errorf("duplicate top-level key: envs")
+		}
+		hasEnvs = true
+		if item.Value == nil {
+			continue
+		}
+
+		var ok bool
+		if envs, ok = item.Value.([]interface{}); !ok {
+			return nil, fmt.Errorf("envs should be a list")
+		}
+	}
+
+	var warnings []string
+	seen := map[string]bool{}
+	for i, env := range envs {
+		envModel, ok := env.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("envs[%d] should be a map", i)
+		}
+
+		var key string
+		hasOpts := false
+		for _, item := range envModel {
+			itemKey, ok := item.Key.(string)
+			if !ok {
+				return nil, fmt.Errorf("envs[%d]: key %v should be a string", i, item.Key)
+			}
+			if itemKey == "opts" {
+				if hasOpts {
+					return nil, fmt.Errorf("envs[%d]: duplicate key: opts", i)
+				}
+				hasOpts = true
+				if _, ok := item.Value.(yaml.MapSlice); !ok && item.Value != nil {
+					return nil, fmt.Errorf("envs[%d]: opts should be a map", i)
+				}
+				continue
+			}
+			if key != "" {
+				return nil, fmt.Errorf("envs[%d]: multiple keys (%s, %s), each list item should define a single secret", i, key, itemKey)
+			}
+			key = itemKey
+
+			value, ok := item.Value.(string)
+			if !ok && item.Value != nil {
+				return nil, fmt.Errorf("envs[%d]: %s should have a string value, got %T, quote the value", i, key, item.Value)
+			}
+			if value == "" {
+				warnings = append(warnings, fmt.Sprintf("%s has an empty value", key))
+			}
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("envs[%d]: no secret key defined", i)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("envs[%d]: duplicate key: %s", i, key)
+		}
+		seen[key] = true
+
+		if !isReferenced(key, references) {
+			warnings = append(warnings, fmt.Sprintf("%s is not referenced by the E2E tests", key))
+		}
+	}
+
+	return warnings, nil
+}
+
+func isReferenced(key string, references []string) bool {
+	pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(key) + `\b`)
+	for _, reference := range references {
+		if pattern.MatchString(reference) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_validateSecretsInventory(t *testing.T) {
+	references := []string{`workflows:
+  test_upload:
+    envs:
+    - TOKEN: $API_TOKEN
+    steps:
+    - script:
+        run_if: '{{enveq "PASSWORD" ""}}'
+`}
+
+	tests := []struct {
+		name      string
+		inventory string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			"Valid inventory",
+			`envs:
+- API_TOKEN: token
+- PASSWORD: "1234"
+  opts:
+    is_expand: false
+`,
+			nil,
+			false,
+		},
+		{
+			"Empty and unreferenced values",
+			`envs:
+- API_TOKEN: ""
+- UNUSED: value
+`,
+			[]string{"API_TOKEN has an empty value", "UNUSED is not referenced by the E2E tests"},
+			false,
+		},
+		{
+			"Wrong top-level key",
+			`app:
+  envs:
+  - API_TOKEN: token
+`,
+			nil,
+			true,
+		},
+		{
+			"Non-string value",
+			`envs:
+- PASSWORD: 1234
+`,
+			nil,
+			true,
+		},
+		{
+			"Duplicate key",
+			`envs:
+- API_TOKEN: token
+- API_TOKEN: other
+`,
+			nil,
+			true,
+		},
+		{
+			"Duplicate envs",
+			`envs:
+- API_TOKEN: token
+envs:
+- PASSWORD: password
+`,
+			nil,
+			true,
+		},
+		{
+			"Duplicate opts",
+			`envs:
+- API_TOKEN: token
+  opts:
+    is_expand: false
+  opts:
+    is_expand: true
+`,
+			nil,
+			true,
+		},
+		{
+			"Multiple keys in one item",
+			`envs:
+- API_TOKEN: token
+  PASSWORD: password
+`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateSecretsInventory([]byte(tt.inventory), references)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSecretsInventory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSecretsInventory() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
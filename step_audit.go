@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// toolkitRequiredFields are the required fields of the supported step toolkits.
+var toolkitRequiredFields = map[string]string{
+	"go":   "package_name",
+	"bash": "entry_file",
+}
+
+// stepAuditRules describe the rules of the step.yml audit by rule ID.
+var stepAuditRules = map[string]string{
+	"structure":          "step.yml must be a mapping",
+	"required-field":     "Required fields must be set and not empty",
+	"field-type":         "Fields must have the expected type",
+	"description":        "The step should have a description",
+	"url":                "URLs must be http(s) URLs",
+	"toolkit":            "The toolkit must be one of the supported toolkits, with its required field",
+	"env-structure":      "Inputs and outputs must be lists of single env key-value pairs",
+	"env-key":            "Env keys must be valid environment variable names",
+	"duplicate-env-key":  "Env keys must be unique",
+	"env-value":          "Env values must be scalars",
+	"env-opts":           "Inputs and outputs must have opts",
+	"unknown-opts-field": "Opts should only have known fields",
+	"env-title":          "Inputs and outputs must have a title",
+	"sensitive-input":    "Sensitive inputs must be expanded",
+	"value-options":      "Value options must be unique scalars, and include the default value",
+}
+
+// envOptsFields are the known fields of the input and output opts.
+var envOptsFields = map[string]bool{
+	"title": true, "summary": true, "description": true, "category": true, "value_options": true,
+	"is_required": true, "is_expand": true, "is_sensitive": true, "is_dont_change_value": true,
+	"is_template": true, "skip_if_empty": true, "unset": true, "meta": true,
+}
+
+// yamlBoolValues are the plain scalars, which stepman's YAML 1.1 parser (yaml.v2) reads as bools.
+var yamlBoolValues = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"true": true, "True": true, "TRUE": true,
+	"on": true, "On": true, "ON": true,
+	"n": false, "N": false, "no": false, "No": false, "NO": false,
+	"false": false, "False": false, "FALSE": false,
+	"off": false, "Off": false, "OFF": false,
+}
+
+// stepAuditor checks step.yml against the rules of the step library, replacing `stepman audit`.
+type stepAuditor struct {
+	file        string
+	diagnostics []diagnostic
+}
+
+// auditStepYML audits the step.yml, replacing `stepman audit`.
+func auditStepYML(stepYMLPath string) ([]diagnostic, error) {
+	stepBytes, err := ioutil.ReadFile(stepYMLPath)
+	if err != nil {
+		return nil, err
+	}
+	return auditStepYMLFromBytes(stepYMLPath, stepBytes)
+}
+
+func auditStepYMLFromBytes(file string, stepBytes []byte) ([]diagnostic, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(stepBytes, &document); err != nil {
+		return nil, fmt.Errorf("invalid step.yml (%s): %w", file, err)
+	}
+
+	a := &stepAuditor{file: file}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		a.report(&document, severityError, "structure", "step.yml must be a mapping")
+		return a.diagnostics, nil
+	}
+
+	root := document.Content[0]
+	for _, key := range []string{"title", "summary", "website"} {
+		a.requireString(root, key)
+	}
+	if _, value := mappingEntry(root, "description"); value == nil {
+		a.report(root, severityWarning, "description", "missing description")
+	}
+	for _, key := range []string{"website", "source_code_url", "support_url"} {
+		if _, value := mappingEntry(root, key); value != nil {
+			a.checkURL(key, value)
+		}
+	}
+	if keyNode, value := mappingEntry(root, "toolkit"); value != nil {
+		a.checkToolkit(keyNode, value)
+	}
+	for _, section := range []string{"inputs", "outputs"} {
+		if _, value := mappingEntry(root, section); value != nil {
+			a.checkEnvs(section, value)
+		}
+	}
+
+	return a.diagnostics, nil
+}
+
+func (a *stepAuditor) report(node *yaml.Node, severity, rule, format string, args ...interface{}) {
+	a.diagnostics = append(a.diagnostics, diagnostic{
+		Check:    checkAudit,
+		Rule:     rule,
+		File:     a.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (a *stepAuditor) requireString(mapping *yaml.Node, key string) {
+	_, value := mappingEntry(mapping, key)
+	switch {
+	case value == nil:
+		a.report(mapping, severityError, "required-field", "missing required field: %s", key)
+	case value.Kind != yaml.ScalarNode || value.Tag != "!!str":
+		a.report(value, severityError, "field-type", "%s must be a string", key)
+	case strings.TrimSpace(value.Value) == "":
+		a.report(value, severityError, "required-field", "%s must not be empty", key)
+	}
+}
+
+func (a *stepAuditor) checkURL(key string, value *yaml.Node) {
+	u, err := url.Parse(value.Value)
+	if value.Kind != yaml.ScalarNode || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		a.report(value, severityError, "url", "%s must be an http(s) URL, got: %s", key, value.Value)
+	}
+}
+
+func (a *stepAuditor) checkToolkit(keyNode, toolkit *yaml.Node) {
+	if toolkit.Kind != yaml.MappingNode {
+		a.report(toolkit, severityError, "toolkit", "toolkit must be a mapping")
+		return
+	}
+	if len(toolkit.Content) != 2 {
+		a.report(keyNode, severityError, "toolkit", "toolkit must define exactly one of: bash, go")
+	}
+
+	for i := 0; i < len(toolkit.Content); i += 2 {
+		name, config := toolkit.Content[i], toolkit.Content[i+1]
+		requiredField, ok := toolkitRequiredFields[name.Value]
+		if !ok {
+			a.report(name, severityError, "toolkit", "unknown toolkit: %s", name.Value)
+			continue
+		}
+		if config.Kind != yaml.MappingNode {
+			a.report(config, severityError, "toolkit", "toolkit.%s must be a mapping", name.Value)
+			continue
+		}
+		a.requireString(config, requiredField)
+	}
+}
+
+// checkEnvs checks the inputs or outputs, each item is a single env key-value pair and its opts.
+func (a *stepAuditor) checkEnvs(section string, envs *yaml.Node) {
+	if envs.Kind != yaml.SequenceNode {
+		a.report(envs, severityError, "env-structure", "%s must be a list", section)
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, env := range envs.Content {
+		if env.Kind != yaml.MappingNode {
+			a.report(env, severityError, "env-structure", "%s item must be a mapping", section)
+			continue
+		}
+
+		var keyNode, valueNode, optsNode *yaml.Node
+		for i := 0; i < len(env.Content); i += 2 {
+			if env.Content[i].Value == "opts" {
+				optsNode = env.Content[i+1]
+				continue
+			}
+			if keyNode != nil {
+				a.report(env.Content[i], severityError, "env-structure", "%s item must have a single env key, found: %s and %s", section, keyNode.Value, env.Content[i].Value)
+				continue
+			}
+			keyNode, valueNode = env.Content[i], env.Content[i+1]
+		}
+		if keyNode == nil {
+			a.report(env, severityError, "env-structure", "%s item without env key", section)
+			continue
+		}
+
+		key := keyNode.Value
+		if !envKeyRegexp.MatchString(key) {
+			a.report(keyNode, severityError, "env-key", "invalid env key: %s", key)
+		}
+		if seen[key] {
+			a.report(keyNode, severityError, "duplicate-env-key", "duplicate %s key: %s", section, key)
+		}
+		seen[key] = true
+		if valueNode.Kind != yaml.ScalarNode {
+			a.report(valueNode, severityError, "env-value", "%s: value must be a scalar", key)
+		}
+
+		if optsNode == nil {
+			a.report(keyNode, severityError, "env-opts", "%s: missing opts", key)
+			continue
+		}
+		a.checkEnvOpts(key, valueNode, optsNode)
+	}
+}
+
+func (a *stepAuditor) checkEnvOpts(key string, value, opts *yaml.Node) {
+	if opts.Kind != yaml.MappingNode {
+		a.report(opts, severityError, "env-opts", "%s: opts must be a mapping", key)
+		return
+	}
+
+	for i := 0; i < len(opts.Content); i += 2 {
+		field, fieldValue := opts.Content[i], opts.Content[i+1]
+		if !envOptsFields[field.Value] {
+			a.report(field, severityWarning, "unknown-opts-field", "%s: unknown opts field: %s", key, field.Value)
+		}
+		if _, ok := yamlBool(fieldValue); strings.HasPrefix(field.Value, "is_") && !ok {
+			a.report(fieldValue, severityError, "field-type", "%s: %s must be a bool", key, field.Value)
+		}
+	}
+
+	// Like stepman, a title is required for both the inputs and the outputs
+	if _, title := mappingEntry(opts, "title"); title == nil || strings.TrimSpace(title.Value) == "" {
+		a.report(opts, severityError, "env-title", "%s: missing title", key)
+	}
+	if _, sensitive := mappingEntry(opts, "is_sensitive"); sensitive != nil {
+		if isSensitive, _ := yamlBool(sensitive); isSensitive {
+			if _, expand := mappingEntry(opts, "is_expand"); expand != nil {
+				if isExpand, ok := yamlBool(expand); ok && !isExpand {
+					a.report(expand, severityError, "sensitive-input", "%s: sensitive inputs must be expanded", key)
+				}
+			}
+		}
+	}
+
+	if _, options := mappingEntry(opts, "value_options"); options != nil {
+		a.checkValueOptions(key, value, options)
+	}
+}
+
+// yamlBool returns the value of a bool scalar, as stepman reads it. ok is false if the node is not a bool.
+func yamlBool(node *yaml.Node) (value bool, ok bool) {
+	if node.Kind != yaml.ScalarNode || node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+		return false, false
+	}
+	if node.Style&yaml.TaggedStyle != 0 && node.Tag != "!!bool" {
+		return false, false
+	}
+	value, ok = yamlBoolValues[node.Value]
+	return value, ok
+}
+
+func (a *stepAuditor) checkValueOptions(key string, value, options *yaml.Node) {
+	if options.Kind != yaml.SequenceNode {
+		a.report(options, severityError, "value-options", "%s: value_options must be a list", key)
+		return
+	}
+	if len(options.Content) < 2 {
+		a.report(options, severityWarning, "value-options", "%s: value_options should have at least 2 items", key)
+	}
+
+	seen := map[string]bool{}
+	for _, option := range options.Content {
+		if option.Kind != yaml.ScalarNode {
+			a.report(option, severityError, "value-options", "%s: value option must be a scalar", key)
+			continue
+		}
+		if seen[option.Value] {
+			a.report(option, severityError, "value-options", "%s: duplicate value option: %s", key, option.Value)
+		}
+		seen[option.Value] = true
+	}
+
+	if value.Kind == yaml.ScalarNode && value.Value != "" && !seen[value.Value] {
+		a.report(value, severityError, "value-options", "%s: default value %q is not one of the value_options", key, value.Value)
+	}
+}
+
+// mappingEntry returns the key and value nodes of a mapping node's field, or nils if it is not found.
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func Test_auditStepYMLFromBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		stepYML string
+		want    []string
+		wantErr bool
+	}{
+		{
+			"Valid",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+toolkit:
+  go:
+    package_name: github.com/bitrise-steplib/steps-check
+inputs:
+- mode: "fast"
+  opts:
+    title: Mode
+    is_required: true
+    value_options:
+    - "fast"
+    - "slow"
+outputs:
+- RESULT:
+  opts:
+    title: Result
+`,
+			nil,
+			false,
+		},
+		{
+			"Missing fields and invalid URL",
+			`title: Step
+summary: ""
+website: github.com/bitrise-steplib/steps-check
+`,
+			[]string{
+				"step.yml:2:10: error: summary must not be empty (required-field)",
+				"step.yml:1:1: warning: missing description (description)",
+				"step.yml:3:10: error: website must be an http(s) URL, got: github.com/bitrise-steplib/steps-check (url)",
+			},
+			false,
+		},
+		{
+			"Invalid toolkit",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+toolkit:
+  go: {}
+  swift: {}
+`,
+			[]string{
+				"step.yml:5:1: error: toolkit must define exactly one of: bash, go (toolkit)",
+				"step.yml:6:7: error: missing required field: package_name (required-field)",
+				"step.yml:7:3: error: unknown toolkit: swift (toolkit)",
+			},
+			false,
+		},
+		{
+			"Invalid envs",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+inputs:
+- mode: "medium"
+  opts:
+    title: Mode
+    is_required: "yes"
+    value_options:
+    - "fast"
+    - "fast"
+- mode: fast
+  other: value
+  opts:
+    title: Mode
+    unknown: value
+- 1-key: value
+outputs:
+- RESULT:
+`,
+			[]string{
+				"step.yml:9:18: error: mode: is_required must be a bool (field-type)",
+				"step.yml:12:7: error: mode: duplicate value option: fast (value-options)",
+				"step.yml:6:9: error: mode: default value \"medium\" is not one of the value_options (value-options)",
+				"step.yml:14:3: error: inputs item must have a single env key, found: mode and other (env-structure)",
+				"step.yml:13:3: error: duplicate inputs key: mode (duplicate-env-key)",
+				"step.yml:17:5: warning: mode: unknown opts field: unknown (unknown-opts-field)",
+				"step.yml:18:3: error: invalid env key: 1-key (env-key)",
+				"step.yml:18:3: error: 1-key: missing opts (env-opts)",
+				"step.yml:20:3: error: RESULT: missing opts (env-opts)",
+			},
+			false,
+		},
+		{
+			"YAML 1.1 bools and output without title",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+inputs:
+- token: ""
+  opts:
+    title: Token
+    is_required: yes
+    is_sensitive: On
+    is_expand: off
+- verbose: "no"
+  opts:
+    title: Verbose
+    is_dont_change_value: N
+    is_template: 1
+outputs:
+- RESULT:
+  opts:
+    summary: Result
+`,
+			[]string{
+				"step.yml:11:16: error: token: sensitive inputs must be expanded (sensitive-input)",
+				"step.yml:16:18: error: verbose: is_template must be a bool (field-type)",
+				"step.yml:20:5: error: RESULT: missing title (env-title)",
+			},
+			false,
+		},
+		{
+			"Not a mapping",
+			`- title`,
+			[]string{"step.yml:1:1: error: step.yml must be a mapping (structure)"},
+			false,
+		},
+		{
+			"Invalid YAML",
+			`title: [`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := auditStepYMLFromBytes("step.yml", []byte(tt.stepYML))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("auditStepYMLFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditStepYMLFromBytes() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_auditStepYMLFromBytes_repoStepYML(t *testing.T) {
+	stepBytes, err := ioutil.ReadFile("step.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := auditStepYMLFromBytes("step.yml", stepBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if issue.Severity == severityError {
+			t.Errorf("unexpected issue: %s", issue)
+		}
+	}
+}
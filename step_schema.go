@@ -0,0 +1,243 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// stepSchema is a verbatim copy of the step.yml schema of https://github.com/bitrise-io/bitrise-json-schemas,
+// downloaded from stepSchemaURL. Refresh it with `go generate`, instead of editing it.
+//
+//go:generate curl -sSfL -o step.schema.json https://raw.githubusercontent.com/bitrise-io/bitrise-json-schemas/main/step.schema.json
+//go:embed step.schema.json
+var stepSchema string
+
+const stepSchemaURL = "https://raw.githubusercontent.com/bitrise-io/bitrise-json-schemas/main/step.schema.json"
+
+// schemaSeverityOverride downgrades the schema violations at the matching step.yml location.
+// Path is the location of the violating value, `*` matches any single segment.
+// Keyword, if set, has to match the end of the violated schema keyword's location.
+type schemaSeverityOverride struct {
+	Path     string
+	Keyword  string
+	Severity string
+}
+
+// stepSchemaSeverityOverrides lists the schema violations, which are accepted in step repos, but not in the step library.
+var stepSchemaSeverityOverrides = []schemaSeverityOverride{
+	{Path: "#", Keyword: "additionalProperties", Severity: severityWarning},
+	{Path: "#", Keyword: "required", Severity: severityWarning},
+	{Path: "#/summary", Keyword: "pattern", Severity: severityWarning},
+	{Path: "#/inputs/*/opts", Keyword: "required", Severity: severityWarning},
+	{Path: "#/outputs/*/opts", Keyword: "required", Severity: severityWarning},
+	{Path: "#/inputs/*/opts/summary", Keyword: "minLength", Severity: severityWarning},
+	{Path: "#/outputs/*/opts/summary", Keyword: "minLength", Severity: severityWarning},
+	{Path: "#/inputs/*/*", Keyword: "additionalProperties/type", Severity: severityWarning},
+	{Path: "#/inputs/*/opts/value_options", Keyword: "minItems", Severity: severityWarning},
+	{Path: "#/deps/brew/*/name", Keyword: "not", Severity: severityWarning},
+	{Path: "#/deps/brew/*/bin_name", Keyword: "not", Severity: severityWarning},
+	{Path: "#/deps/apt_get/*/name", Keyword: "not", Severity: severityWarning},
+	{Path: "#/deps/apt_get/*/bin_name", Keyword: "not", Severity: severityWarning},
+}
+
+// validateStepSchema validates the step.yml against the embedded step.yml JSON schema.
+func validateStepSchema(stepYMLPath string) ([]diagnostic, error) {
+	stepBytes, err := ioutil.ReadFile(stepYMLPath)
+	if err != nil {
+		return nil, err
+	}
+	return validateStepSchemaFromBytes(stepYMLPath, stepBytes, stepSchemaSeverityOverrides)
+}
+
+func validateStepSchemaFromBytes(file string, stepBytes []byte, overrides []schemaSeverityOverride) ([]diagnostic, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(stepSchemaURL, strings.NewReader(stepSchema)); err != nil {
+		return nil, err
+	}
+	schema, err := compiler.Compile(stepSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step.yml schema: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(stepBytes, &document); err != nil {
+		return nil, fmt.Errorf("invalid step.yml (%s): %w", file, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("empty step.yml (%s)", file)
+	}
+	root := document.Content[0]
+
+	err = schema.Validate(yamlNodeToJSON(root))
+	var validationErr *jsonschema.ValidationError
+	if err == nil {
+		return nil, nil
+	} else if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var issues []diagnostic
+	for _, leaf := range validationErrorLeaves(validationErr) {
+		node := schemaErrorNode(root, leaf)
+		issues = append(issues, diagnostic{
+			Check:    checkSchema,
+			Rule:     leaf.KeywordLocation[strings.LastIndex(leaf.KeywordLocation, "/")+1:],
+			File:     file,
+			Line:     node.Line,
+			Column:   node.Column,
+			Severity: schemaErrorSeverity(leaf, overrides),
+			Message:  fmt.Sprintf("%s: %s", instancePath(leaf.InstanceLocation), leaf.Message),
+		})
+	}
+
+	// The validator reports object properties in random order
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		if issues[i].Column != issues[j].Column {
+			return issues[i].Column < issues[j].Column
+		}
+		return issues[i].Message < issues[j].Message
+	})
+	return issues, nil
+}
+
+func validationErrorLeaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, validationErrorLeaves(cause)...)
+	}
+	return leaves
+}
+
+func instancePath(instanceLocation string) string {
+	return "#" + instanceLocation
+}
+
+func schemaErrorSeverity(err *jsonschema.ValidationError, overrides []schemaSeverityOverride) string {
+	path := strings.Split(instancePath(err.InstanceLocation), "/")
+	for _, override := range overrides {
+		if override.Keyword != "" && !strings.HasSuffix(err.KeywordLocation, "/"+override.Keyword) {
+			continue
+		}
+
+		pattern := strings.Split(override.Path, "/")
+		if len(pattern) != len(path) {
+			continue
+		}
+		matches := true
+		for i := range pattern {
+			if pattern[i] != "*" && pattern[i] != path[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return override.Severity
+		}
+	}
+	return severityError
+}
+
+// schemaErrorNode returns the YAML node of the violating value. Not allowed properties and invalid property names
+// are located by their keys.
+func schemaErrorNode(root *yaml.Node, err *jsonschema.ValidationError) *yaml.Node {
+	node := root
+	if err.InstanceLocation != "" {
+		tokens := strings.Split(strings.TrimPrefix(err.InstanceLocation, "/"), "/")
+		for i, token := range tokens {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			if i == len(tokens)-1 && strings.Contains(err.KeywordLocation, "/propertyNames/") && node.Kind == yaml.MappingNode {
+				if keyNode, _ := mappingEntry(node, token); keyNode != nil {
+					return keyNode
+				}
+			}
+			child := yamlChildNode(node, token)
+			if child == nil {
+				break
+			}
+			node = child
+		}
+	}
+
+	if strings.HasSuffix(err.KeywordLocation, "/additionalProperties") {
+		if match := regexp.MustCompile(`'([^']*)'`).FindStringSubmatch(err.Message); match != nil {
+			if keyNode, _ := mappingEntry(node, match[1]); keyNode != nil {
+				return keyNode
+			}
+		}
+	}
+	return node
+}
+
+func yamlChildNode(node *yaml.Node, token string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		_, value := mappingEntry(node, token)
+		return value
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil
+		}
+		return node.Content[i]
+	case yaml.AliasNode:
+		return yamlChildNode(node.Alias, token)
+	}
+	return nil
+}
+
+// yamlNodeToJSON converts the YAML node into the value types of encoding/json, expected by the schema validator.
+// Timestamps are kept as strings, like in JSON.
+func yamlNodeToJSON(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return yamlNodeToJSON(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeToJSON(node.Alias)
+	case yaml.MappingNode:
+		m := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = yamlNodeToJSON(node.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := []interface{}{}
+		for _, item := range node.Content {
+			s = append(s, yamlNodeToJSON(item))
+		}
+		return s
+	}
+
+	// Bools are read like stepman reads them, as YAML 1.1 bools
+	if b, ok := yamlBool(node); ok {
+		return b
+	}
+	switch node.Tag {
+	case "!!null":
+		return nil
+	case "!!int", "!!float":
+		var f float64
+		if err := node.Decode(&f); err == nil {
+			return f
+		}
+	}
+	return node.Value
+}
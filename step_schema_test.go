@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func Test_validateStepSchemaFromBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		stepYML   string
+		overrides []schemaSeverityOverride
+		want      []string
+	}{
+		{
+			"Valid",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+source_code_url: https://github.com/bitrise-steplib/steps-check
+support_url: https://github.com/bitrise-steplib/steps-check/issues
+published_at: 2021-01-01T00:00:00.000000000+01:00
+timeout: 60
+inputs:
+- mode: fast
+  opts:
+    title: Mode
+    summary: Mode
+    is_required: yes
+    is_expand: Off
+    value_options:
+    - fast
+    - slow
+outputs:
+- RESULT:
+  opts:
+    title: Result
+    summary: Result
+`,
+			stepSchemaSeverityOverrides,
+			nil,
+		},
+		{
+			"Errors located by YAML line",
+			`title: Step
+summary: Summary
+description: Description
+website: https://github.com/bitrise-steplib/steps-check
+source_code_url: https://github.com/bitrise-steplib/steps-check
+support_url: https://github.com/bitrise-steplib/steps-check/issues
+is_always_run: "yes"
+inputs:
+- mode: fast
+  opts:
+    title: Mode
+    summary: ""
+    is_required: "yes"
+`,
+			nil,
+			[]string{
+				"step.yml:7:16: error: #/is_always_run: expected boolean, but got string (type)",
+				"step.yml:12:14: error: #/inputs/0/opts/summary: length must be >= 1, but got 0 (minLength)",
+				"step.yml:13:18: error: #/inputs/0/opts/is_required: expected boolean, but got string (type)",
+			},
+		},
+		{
+			"Severity overrides",
+			`title: Step
+summary: Summary
+website: https://github.com/bitrise-steplib/steps-check
+unknown: value
+inputs:
+- retries: 3
+  opts:
+    title: Retries
+`,
+			stepSchemaSeverityOverrides,
+			[]string{
+				"step.yml:1:1: warning: #: missing properties: 'description', 'source_code_url', 'support_url' (required)",
+				"step.yml:4:1: warning: #: additionalProperties 'unknown' not allowed (additionalProperties)",
+				"step.yml:6:12: warning: #/inputs/0/retries: expected string, but got number (type)",
+				"step.yml:8:5: warning: #/inputs/0/opts: missing properties: 'summary' (required)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := validateStepSchemaFromBytes("step.yml", []byte(tt.stepYML), tt.overrides)
+			if err != nil {
+				t.Fatalf("validateStepSchemaFromBytes() error = %v", err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateStepSchemaFromBytes() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_schemaErrorSeverity(t *testing.T) {
+	tests := []struct {
+		name             string
+		instanceLocation string
+		keywordLocation  string
+		want             string
+	}{
+		{"Brew dependency", "/deps/brew/0/name", "/properties/deps/$ref/properties/brew/items/$ref/properties/name/not", severityWarning},
+		{"Apt-get dependency binary", "/deps/apt_get/1/bin_name", "/properties/deps/$ref/properties/apt_get/items/$ref/properties/bin_name/not", severityWarning},
+		{"Other keyword", "/deps/brew/0/name", "/properties/deps/$ref/properties/brew/items/$ref/properties/name/minLength", severityError},
+		{"Other location", "/deps/check_only/0/name", "/properties/deps/$ref/properties/check_only/items/$ref/properties/name/not", severityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &jsonschema.ValidationError{InstanceLocation: tt.instanceLocation, KeywordLocation: tt.keywordLocation}
+			if got := schemaErrorSeverity(err, stepSchemaSeverityOverrides); got != tt.want {
+				t.Errorf("schemaErrorSeverity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	outputFormatText   = "text"
+	outputFormatGitHub = "github"
+
+	summaryFileName = "steps-check-summary.md"
+	// summaryTopFindings is the number of findings listed in the Markdown summary, errors first.
+	summaryTopFindings = 10
+)
+
+// checkResult is the outcome of a native check, a check workflow or the E2E tests, as listed in the build summary.
+type checkResult struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	Errors   int
+	Warnings int
+	// Baselined findings are not counted as errors or warnings.
+	Baselined int
+}
+
+func newCheckResult(name string, err error, duration time.Duration, diagnostics []diagnostic) checkResult {
+	result := checkResult{Name: name, Err: err, Duration: duration}
+	for _, d := range diagnostics {
+		switch {
+		case d.Baselined:
+			result.Baselined++
+		case d.Severity == severityError:
+			result.Errors++
+		default:
+			result.Warnings++
+		}
+	}
+	return result
+}
+
+// githubAnnotation formats the diagnostic as a GitHub workflow command, which annotates the file in the PR:
+//
+//	::error file=step.yml,line=3,col=10,title=audit/url::invalid URL
+func githubAnnotation(d diagnostic) string {
+	command := "error"
+	if d.Severity == severityWarning {
+		command = "warning"
+	}
+
+	properties := []string{"file=" + escapeGitHubProperty(d.File)}
+	if d.Line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", d.Line))
+		if d.Column > 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", d.Column))
+		}
+	}
+	properties = append(properties, "title="+escapeGitHubProperty(sarifRuleID(d)))
+
+	message := d.Message
+	if d.Fix != "" {
+		message += "\nFix: " + d.Fix
+	}
+	return fmt.Sprintf("::%s %s::%s", command, strings.Join(properties, ","), escapeGitHubData(message))
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// printGitHubAnnotations prints the not baselined diagnostics as GitHub workflow commands, sorted by their location.
+func printGitHubAnnotations(diagnostics []diagnostic) {
+	sorted := append([]diagnostic(nil), diagnostics...)
+	sortDiagnostics(sorted)
+	for _, d := range sorted {
+		if !d.Baselined {
+			fmt.Println(githubAnnotation(d))
+		}
+	}
+}
+
+// markdownSummary renders the results of the checks as a table, followed by the top findings.
+func markdownSummary(results []checkResult, diagnostics []diagnostic) string {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	var b strings.Builder
+	if failed > 0 {
+		fmt.Fprintf(&b, "## steps-check: %d of %d check(s) failed\n\n", failed, len(results))
+	} else {
+		fmt.Fprintf(&b, "## steps-check: %d check(s) passed\n\n", len(results))
+	}
+
+	if len(results) > 0 {
+		b.WriteString("| Check | Status | Duration | Findings |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, result := range results {
+			status := "✅ passed"
+			if result.Err != nil {
+				status = "❌ failed"
+			}
+			findings := fmt.Sprintf("%d error(s), %d warning(s)", result.Errors, result.Warnings)
+			if result.Baselined > 0 {
+				findings += fmt.Sprintf(", %d baselined", result.Baselined)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+				escapeMarkdownTableCell(result.Name), status, result.Duration.Round(time.Millisecond), findings)
+		}
+	}
+
+	var sorted []diagnostic
+	for _, d := range diagnostics {
+		if !d.Baselined {
+			sorted = append(sorted, d)
+		}
+	}
+	if len(sorted) == 0 {
+		return b.String()
+	}
+	sortDiagnostics(sorted)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Severity == severityError && sorted[j].Severity != severityError
+	})
+
+	b.WriteString("\n### Top findings\n\n")
+	for i, d := range sorted {
+		if i == summaryTopFindings {
+			fmt.Fprintf(&b, "\n…and %d more, see the build log or the SARIF file.\n", len(sorted)-summaryTopFindings)
+			break
+		}
+		fmt.Fprintf(&b, "- **%s** `%s` %s (`%s`)\n", d.Severity, d.location(), strings.ReplaceAll(d.Message, "\n", " "), sarifRuleID(d))
+	}
+	return b.String()
+}
+
+func escapeMarkdownTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// writeMarkdownSummary writes the Markdown summary into the directory, and returns its path.
+func writeMarkdownSummary(dir string, results []checkResult, diagnostics []diagnostic) (string, error) {
+	pth := filepath.Join(dir, summaryFileName)
+	if err := ioutil.WriteFile(pth, []byte(markdownSummary(results, diagnostics)), 0600); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
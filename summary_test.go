@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_githubAnnotation(t *testing.T) {
+	tests := []struct {
+		name string
+		d    diagnostic
+		want string
+	}{
+		{
+			"Error with line and column",
+			diagnostic{Check: checkAudit, Rule: "url", File: "step.yml", Line: 3, Column: 10, Severity: severityError, Message: "invalid URL"},
+			"::error file=step.yml,line=3,col=10,title=audit/url::invalid URL",
+		},
+		{
+			"Warning of the whole file with a fix",
+			diagnostic{Check: checkREADMEDiff, Rule: "out-of-date", File: "README.md", Severity: severityWarning, Message: "100% out of date", Fix: "regenerate it"},
+			"::warning file=README.md,title=readme/out-of-date::100%25 out of date%0AFix: regenerate it",
+		},
+		{
+			"Escaped properties",
+			diagnostic{Check: checkGoTest, Rule: "Test_a,b", File: "a:b_test.go", Line: 7, Severity: severityError, Message: "got: 1, want: 2"},
+			"::error file=a%3Ab_test.go,line=7,title=go-test/Test_a%2Cb::got: 1, want: 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := githubAnnotation(tt.d); got != tt.want {
+				t.Errorf("githubAnnotation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_markdownSummary(t *testing.T) {
+	results := []checkResult{
+		newCheckResult(yamllintCheckTitle, nil, 1500*time.Microsecond, []diagnostic{{Severity: severityWarning}}),
+		newCheckResult("lint workflow", errors.New("exit status 1"), 2*time.Second, []diagnostic{{Severity: severityError}}),
+	}
+	diagnostics := []diagnostic{
+		{Check: checkYAMLLint, Rule: "comments", File: "bitrise.yml", Line: 2, Column: 1, Severity: severityWarning, Message: "missing starting space in comment"},
+		{Check: checkGolangciLint, Rule: "errcheck", File: "main.go", Line: 12, Column: 5, Severity: severityError, Message: "error return value is not checked"},
+	}
+
+	want := `## steps-check: 1 of 2 check(s) failed
+
+| Check | Status | Duration | Findings |
+| --- | --- | --- | --- |
+| YAML lint | ✅ passed | 2ms | 0 error(s), 1 warning(s) |
+| lint workflow | ❌ failed | 2s | 1 error(s), 0 warning(s) |
+
+### Top findings
+
+- **error** ` + "`main.go:12:5`" + ` error return value is not checked (` + "`golangci-lint/errcheck`" + `)
+- **warning** ` + "`bitrise.yml:2:1`" + ` missing starting space in comment (` + "`yamllint/comments`" + `)
+`
+	if got := markdownSummary(results, diagnostics); got != want {
+		t.Errorf("markdownSummary() = %v, want %v", got, want)
+	}
+}
+
+func Test_markdownSummary_truncatesFindings(t *testing.T) {
+	var diagnostics []diagnostic
+	for i := 1; i <= summaryTopFindings+3; i++ {
+		diagnostics = append(diagnostics, diagnostic{Check: checkAudit, File: "step.yml", Line: i, Severity: severityError, Message: fmt.Sprintf("finding %d", i)})
+	}
+
+	got := markdownSummary([]checkResult{newCheckResult(auditCheckTitle, errors.New("failed"), time.Second, diagnostics)}, diagnostics)
+
+	if !strings.HasPrefix(got, "## steps-check: 1 of 1 check(s) failed") {
+		t.Errorf("markdownSummary() title: %v", got)
+	}
+	if n := strings.Count(got, "\n- "); n != summaryTopFindings {
+		t.Errorf("markdownSummary() lists %d findings, want %d", n, summaryTopFindings)
+	}
+	if !strings.Contains(got, "…and 3 more") {
+		t.Errorf("markdownSummary() does not mention the remaining findings: %v", got)
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// yamlfmtConfigFileNames are the repo level config files, which take precedence over the embedded .ymlfmt.
+var yamlfmtConfigFileNames = []string{".yamlfmt", ".yamlfmt.yml", ".yamlfmt.yaml", "yamlfmt.yml", "yamlfmt.yaml", ".ymlfmt"}
+
+// yamlfmtDefaultExcludes are the excludes of the yamlfmt step bundle.
+var yamlfmtDefaultExcludes = []string{"_tmp/**", ".bitrise.secrets.yml", ".git/**", ".github/**", "vendor/**"}
+
+const (
+	yamlfmtLineBreakPlaceholder = "#magic___^_^___line"
+	yamlfmtRule                 = "formatting"
+	yamlfmtRuleDescription      = "YAML files must be formatted with yamlfmt"
+)
+
+var (
+	yamlfmtBlockScalarHeaderRegex = regexp.MustCompile(`(^|[\s:-])[|>][-+0-9]*(\s+#.*)?$`)
+	yamlfmtKeyWithoutValueRegex   = regexp.MustCompile(`:(\s+[&!]\S*)*(\s+#.*)?$`)
+	yamlfmtAliasKeyRegex          = regexp.MustCompile(`^((?:- )*\*\S+):( |$)`)
+)
+
+// yamlfmtConfigModel is the subset of the yamlfmt config file format, which the built-in formatter supports:
+//
+//	line_ending: lf
+//	exclude:
+//	- e2e/testdata/**
+//	formatter:
+//	  type: basic
+//	  indent: 2
+//	  indentless_arrays: true
+type yamlfmtConfigModel struct {
+	LineEnding string                `yaml:"line_ending"`
+	Exclude    []string              `yaml:"exclude"`
+	Formatter  yamlfmtFormatterModel `yaml:"formatter"`
+}
+
+type yamlfmtFormatterModel struct {
+	Type                   string `yaml:"type"`
+	Indent                 int    `yaml:"indent"`
+	IndentlessArrays       bool   `yaml:"indentless_arrays"`
+	RetainLineBreaks       bool   `yaml:"retain_line_breaks"`
+	RetainLineBreaksSingle bool   `yaml:"retain_line_breaks_single"`
+	TrimTrailingWhitespace bool   `yaml:"trim_trailing_whitespace"`
+	EOFNewline             bool   `yaml:"eof_newline"`
+	IncludeDocumentStart   bool   `yaml:"include_document_start"`
+	MaxLineLength          int    `yaml:"max_line_length"`
+	ForceQuoteStyle        string `yaml:"force_quote_style"`
+	PadLineComments        int    `yaml:"pad_line_comments"`
+	DropMergeTag           bool   `yaml:"drop_merge_tag"`
+}
+
+// yamlFormatter formats YAML files the same way as yamlfmt's basic formatter.
+type yamlFormatter struct {
+	lineBreak string
+	exclude   []string
+	formatter yamlfmtFormatterModel
+}
+
+func parseYAMLFmtConfig(configBytes []byte) (*yamlFormatter, error) {
+	model := yamlfmtConfigModel{Formatter: yamlfmtFormatterModel{Type: "basic", Indent: 2, PadLineComments: 1}}
+	if err := yaml.UnmarshalStrict(configBytes, &model); err != nil {
+		return nil, err
+	}
+
+	formatter := &yamlFormatter{
+		lineBreak: "\n",
+		exclude:   append(append([]string{}, yamlfmtDefaultExcludes...), model.Exclude...),
+		formatter: model.Formatter,
+	}
+	switch model.LineEnding {
+	case "", "lf":
+	case "crlf":
+		formatter.lineBreak = "\r\n"
+	default:
+		return nil, fmt.Errorf("unsupported line_ending: %s", model.LineEnding)
+	}
+
+	switch {
+	case model.Formatter.Type != "basic":
+		return nil, fmt.Errorf("unsupported formatter type: %s", model.Formatter.Type)
+	case model.Formatter.Indent < 1:
+		return nil, fmt.Errorf("invalid indent: %d", model.Formatter.Indent)
+	case model.Formatter.IncludeDocumentStart:
+		return nil, fmt.Errorf("include_document_start is not supported, set it to false")
+	case model.Formatter.MaxLineLength != 0:
+		return nil, fmt.Errorf("max_line_length is not supported, set it to 0")
+	case model.Formatter.PadLineComments != 1:
+		return nil, fmt.Errorf("pad_line_comments is not supported, set it to 1")
+	}
+	switch model.Formatter.ForceQuoteStyle {
+	case "", "double", "single":
+	default:
+		return nil, fmt.Errorf("unsupported force_quote_style: %s", model.Formatter.ForceQuoteStyle)
+	}
+
+	return formatter, nil
+}
+
+// readYAMLFmtConfig reads the repo level yamlfmt config of the directory, falling back to the given config.
+func readYAMLFmtConfig(dir, fallbackConfig string) (*yamlFormatter, error) {
+	for _, name := range yamlfmtConfigFileNames {
+		pth := filepath.Join(dir, name)
+		configBytes, err := ioutil.ReadFile(pth)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		formatter, err := parseYAMLFmtConfig(configBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid yamlfmt config (%s): %w", pth, err)
+		}
+		return formatter, nil
+	}
+
+	formatter, err := parseYAMLFmtConfig([]byte(fallbackConfig))
+	if err != nil {
+		return nil, fmt.Errorf("invalid yamlfmt config: %w", err)
+	}
+	return formatter, nil
+}
+
+// formatYAMLFiles checks if the .yml files of the directory are formatted, the diff of the ones which are not
+// is reported in the details of their findings.
+// If write is set, the files are formatted in place instead.
+func formatYAMLFiles(dir, fallbackConfig string, write bool) ([]diagnostic, error) {
+	formatter, err := readYAMLFmtConfig(dir, fallbackConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []diagnostic
+	var formatted []string
+	if err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, pth)
+		if err != nil {
+			return err
+		}
+		if rel != "." && formatter.isExcluded(filepath.ToSlash(rel), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || filepath.Ext(pth) != ".yml" {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(pth)
+		if err != nil {
+			return err
+		}
+		formattedContent, err := formatter.format(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", rel, err)
+		}
+		if formattedContent == string(content) {
+			return nil
+		}
+
+		if write {
+			formatted = append(formatted, rel)
+			return ioutil.WriteFile(pth, []byte(formattedContent), info.Mode())
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Check:    checkYAMLFmt,
+			Rule:     yamlfmtRule,
+			File:     rel,
+			Line:     firstChangedLine(string(content), formattedContent),
+			Severity: severityError,
+			Message:  "file is not formatted",
+			Fix:      "run `steps-check yamlfmt -w` to format it",
+			Details:  unifiedDiff(rel, rel+" (formatted)", string(content), formattedContent),
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(formatted) > 0 {
+		log.Donef("Formatted %d file(s): %s", len(formatted), strings.Join(formatted, ", "))
+	}
+	return diagnostics, nil
+}
+
+// isExcluded matches the slash separated relative path against the exclude patterns.
+func (f *yamlFormatter) isExcluded(rel string, isDir bool) bool {
+	return matchesExcludePattern(f.exclude, rel, isDir)
+}
+
+// format returns the formatted content. Like yamlfmt, the formatting is done by encoding the parsed documents,
+// surrounded by text based pre- and post-processing of the features, which the YAML encoder doesn't support.
+func (f *yamlFormatter) format(content string) (string, error) {
+	if content == "" {
+		return content, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	if f.formatter.RetainLineBreaks || f.formatter.RetainLineBreaksSingle {
+		lines = replaceYAMLLineBreaks(lines, f.formatter.RetainLineBreaksSingle)
+	}
+	if f.formatter.TrimTrailingWhitespace {
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+	}
+
+	var documents []*yamlv3.Node
+	decoder := yamlv3.NewDecoder(strings.NewReader(strings.Join(lines, "\n")))
+	for {
+		var document yamlv3.Node
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		documents = append(documents, &document)
+	}
+	if len(documents) == 0 {
+		return content, nil
+	}
+
+	var b strings.Builder
+	encoder := yamlv3.NewEncoder(&b)
+	encoder.SetIndent(f.formatter.Indent)
+	for _, document := range documents {
+		switch f.formatter.ForceQuoteStyle {
+		case "double":
+			forceYAMLQuoteStyle(document, yamlv3.SingleQuotedStyle, yamlv3.DoubleQuotedStyle)
+		case "single":
+			forceYAMLQuoteStyle(document, yamlv3.DoubleQuotedStyle, yamlv3.SingleQuotedStyle)
+		}
+		if err := encoder.Encode(document); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	formatted := f.fixBlockLines(b.String())
+	if f.formatter.RetainLineBreaks || f.formatter.RetainLineBreaksSingle {
+		formatted = restoreYAMLLineBreaks(formatted)
+	}
+	if f.formatter.EOFNewline && !strings.HasSuffix(formatted, "\n") {
+		formatted += "\n"
+	}
+	if f.lineBreak != "\n" {
+		formatted = strings.ReplaceAll(formatted, "\n", f.lineBreak)
+	}
+	return formatted, nil
+}
+
+// replaceYAMLLineBreaks replaces blank lines with placeholder comments, so that the encoder keeps them.
+// The placeholders are indented at least as much as the preceding lines, to stay inside of block scalars.
+func replaceYAMLLineBreaks(lines []string, single bool) []string {
+	var replaced []string
+	padding := 0
+	inLineBreaks := false
+	for _, line := range lines {
+		if indent := len(line) - len(strings.TrimLeft(line, " ")); indent > padding {
+			padding = indent
+		}
+		if strings.TrimSpace(line) != "" {
+			replaced = append(replaced, line)
+			inLineBreaks = false
+			continue
+		}
+		if single && inLineBreaks {
+			continue
+		}
+		replaced = append(replaced, strings.Repeat(" ", padding)+yamlfmtLineBreakPlaceholder)
+		inLineBreaks = true
+	}
+	return replaced
+}
+
+// restoreYAMLLineBreaks turns the placeholder comments back into blank lines,
+// and drops the blank lines added by the encoder.
+func restoreYAMLLineBreaks(content string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, yamlfmtLineBreakPlaceholder) {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func forceYAMLQuoteStyle(node *yamlv3.Node, from, to yamlv3.Style) {
+	for _, child := range node.Content {
+		if child.Style == from {
+			child.Style = to
+		}
+		forceYAMLQuoteStyle(child, from, to)
+	}
+}
+
+// yamlfmtSequenceLevel is a block sequence of a mapping, its lines are shifted left to make it indentless.
+type yamlfmtSequenceLevel struct {
+	indent int
+	shift  int
+}
+
+// fixBlockLines fixes the encoded lines, which are encoded differently by yamlfmt's fork of the YAML library:
+// sequences of mappings are indentless, alias keys are separated from the colon and merge keys are not tagged.
+// The content lines of block scalars are shifted together with their key.
+func (f *yamlFormatter) fixBlockLines(content string) string {
+	lines := strings.Split(content, "\n")
+	fixed := make([]string, len(lines))
+
+	var levels []yamlfmtSequenceLevel
+	shiftAt := func(levels []yamlfmtSequenceLevel, indent int) int {
+		for i := len(levels) - 1; i >= 0; i-- {
+			if levels[i].indent <= indent {
+				return levels[i].shift
+			}
+		}
+		return 0
+	}
+
+	// Comments are shifted after the next line is processed, as they may belong to a sequence starting on it
+	var pendingComments []int
+	keyColumn := -1
+	blockScalarIndent, blockScalarShift := -1, 0
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+
+		if blockScalarIndent >= 0 {
+			if trimmed == "" || indent > blockScalarIndent {
+				fixed[i] = shiftYAMLLine(line, blockScalarShift)
+				continue
+			}
+			blockScalarIndent = -1
+		}
+		if trimmed == "" {
+			fixed[i] = line
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			pendingComments = append(pendingComments, i)
+			continue
+		}
+
+		previousLevels := append([]yamlfmtSequenceLevel(nil), levels...)
+		for len(levels) > 0 && levels[len(levels)-1].indent > indent {
+			levels = levels[:len(levels)-1]
+		}
+		isSequenceItem := strings.HasPrefix(trimmed, "- ") || trimmed == "-"
+		if f.formatter.IndentlessArrays && isSequenceItem && indent == keyColumn+f.formatter.Indent &&
+			(len(levels) == 0 || levels[len(levels)-1].indent < indent) {
+			levels = append(levels, yamlfmtSequenceLevel{indent: indent, shift: shiftAt(levels, indent) + f.formatter.Indent})
+		}
+		shift := shiftAt(levels, indent)
+
+		for _, j := range pendingComments {
+			commentIndent := len(lines[j]) - len(strings.TrimLeft(lines[j], " "))
+			if commentIndent == indent {
+				fixed[j] = shiftYAMLLine(lines[j], shift)
+			} else {
+				fixed[j] = shiftYAMLLine(lines[j], shiftAt(previousLevels, commentIndent))
+			}
+		}
+		pendingComments = nil
+
+		trimmed = yamlfmtAliasKeyRegex.ReplaceAllString(trimmed, "$1 :$2")
+		if f.formatter.DropMergeTag {
+			trimmed = strings.Replace(trimmed, "!!merge <<:", "<<:", 1)
+		}
+		fixed[i] = shiftYAMLLine(strings.Repeat(" ", indent)+trimmed, shift)
+
+		keyColumn = -1
+		if yamlfmtKeyWithoutValueRegex.MatchString(trimmed) {
+			keyColumn = indent
+			for strings.HasPrefix(line[keyColumn:], "- ") {
+				keyColumn += 2
+			}
+		}
+		if yamlfmtBlockScalarHeaderRegex.MatchString(trimmed) {
+			blockScalarIndent, blockScalarShift = indent, shift
+		}
+	}
+	for _, j := range pendingComments {
+		fixed[j] = shiftYAMLLine(lines[j], shiftAt(levels, len(lines[j])-len(strings.TrimLeft(lines[j], " "))))
+	}
+
+	return strings.Join(fixed, "\n")
+}
+
+func shiftYAMLLine(line string, shift int) string {
+	if shift == 0 || strings.TrimSpace(line) == "" {
+		return line
+	}
+	return line[shift:]
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_yamlFormatter_format(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Formatted",
+			content: "workflows:\n  test:\n    steps:\n    - script@1:\n        inputs:\n        - content: |-\n            echo \"hello\"\n",
+			want:    "workflows:\n  test:\n    steps:\n    - script@1:\n        inputs:\n        - content: |-\n            echo \"hello\"\n",
+		},
+		{
+			name:    "Indented sequences",
+			content: "steps:\n    -   script:\n            inputs:\n                - content: |-\n                    - not an item\n                - mode: 1\n",
+			want:    "steps:\n- script:\n    inputs:\n    - content: |-\n        - not an item\n    - mode: 1\n",
+		},
+		{
+			name:    "Comments of indentless sequences",
+			content: "steps:\n  # first\n  - a\n  - b:\n      - c\n      # foot\nnext: 1\n",
+			want:    "steps:\n# first\n- a\n- b:\n  - c\n  # foot\nnext: 1\n",
+		},
+		{
+			name:    "Line breaks, trailing whitespace and EOF newline",
+			content: "a: 1   \n\n\n\nb: |\n  line  \n\n  line\nc: 2",
+			want:    "a: 1\n\nb: |\n  line\n\n  line\nc: 2\n",
+		},
+		{
+			name:    "Double quotes",
+			content: "a: 'single'\nb: 'it''s'\nc: [x, 'y']\nd: \"double\"\n",
+			want:    "a: \"single\"\nb: \"it's\"\nc: [x, \"y\"]\nd: \"double\"\n",
+		},
+		{
+			name:    "Anchors and aliases",
+			content: "base: &base\n  - x\nkey: &key name\nkeys:\n  *key : value\n",
+			want:    "base: &base\n- x\nkey: &key name\nkeys:\n  *key : value\n",
+		},
+		{
+			name:    "Multiple documents",
+			content: "a: 1\n---\nb:\n  - 2\n",
+			want:    "a: 1\n---\nb:\n- 2\n",
+		},
+		{
+			name:    "CRLF line endings",
+			content: "a: 1\r\nb:\r\n  - 2\r\n",
+			want:    "a: 1\nb:\n- 2\n",
+		},
+		{
+			name:    "Empty file",
+			content: "",
+			want:    "",
+		},
+	}
+	formatter, err := parseYAMLFmtConfig([]byte(yamlfmtConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatter.format(tt.content)
+			if err != nil {
+				t.Fatalf("format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("format() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseYAMLFmtConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"Embedded config", yamlfmtConfig, false},
+		{"Empty config", "", false},
+		{"CRLF line ending", "line_ending: crlf\n", false},
+		{"Unknown formatter option", "formatter:\n  unknown: true\n", true},
+		{"Unsupported formatter type", "formatter:\n  type: kyaml\n", true},
+		{"Unsupported max line length", "formatter:\n  max_line_length: 80\n", true},
+		{"Unsupported quote style", "formatter:\n  force_quote_style: backtick\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYAMLFmtConfig([]byte(tt.config)); (err != nil) != tt.wantErr {
+				t.Errorf("parseYAMLFmtConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_yamlFormatter_isExcluded(t *testing.T) {
+	formatter, err := parseYAMLFmtConfig([]byte("exclude:\n- e2e/testdata/**\n- '**/generated.yml'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"vendor", true, true},
+		{"_tmp", true, true},
+		{".bitrise.secrets.yml", false, true},
+		{"e2e/.bitrise.secrets.yml", false, false},
+		{"e2e/testdata", true, true},
+		{"e2e/bitrise.yml", false, false},
+		{"a/b/generated.yml", false, true},
+	}
+	for _, tt := range tests {
+		if got := formatter.isExcluded(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("isExcluded(%s) got = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// yamllintConfigFileNames are the repo level config files, which take precedence over the embedded .yamllint.yml.
+var yamllintConfigFileNames = []string{".yamllint", ".yamllint.yaml", ".yamllint.yml"}
+
+// yamllintConfigModel is the yamllint config file format:
+//
+//	extends: default
+//	ignore: |
+//	  vendor/
+//	rules:
+//	  empty-lines: {max: 1}
+//	  line-length: disable
+//	  comments:
+//	    level: error
+type yamllintConfigModel struct {
+	Extends string                 `yaml:"extends"`
+	Ignore  interface{}            `yaml:"ignore"`
+	Rules   map[string]interface{} `yaml:"rules"`
+}
+
+// yamllintRuleConfig is the resolved config of an enabled rule.
+type yamllintRuleConfig struct {
+	Level   string
+	Options map[string]interface{}
+}
+
+// yamlLinter runs the enabled rules on the files, which are not ignored.
+type yamlLinter struct {
+	ignore []string
+	rules  map[string]yamllintRuleConfig
+	// unsupportedRules are the enabled rules without an implementation, by the line of their config.
+	unsupportedRules map[string]int
+	// configFile is the relative path of the repo's config, empty if the embedded config is used.
+	configFile string
+}
+
+// yamllintFile is a parsed YAML file, shared by the rules.
+type yamllintFile struct {
+	path    string
+	content string
+	lines   []string
+	// documents are nil if the file could not be parsed, only the line based rules run on such files.
+	documents []*yamlv3.Node
+	// scanned is set on the first use, by scan.
+	scanned *yamllintScan
+}
+
+type yamllintProblem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func parseYAMLLintConfig(configBytes []byte) (*yamlLinter, error) {
+	var model yamllintConfigModel
+	if err := yaml.Unmarshal(configBytes, &model); err != nil {
+		return nil, err
+	}
+
+	linter := &yamlLinter{rules: map[string]yamllintRuleConfig{}, unsupportedRules: map[string]int{}}
+	switch model.Extends {
+	case "":
+	case "default":
+		for name, level := range yamllintDefaultPreset {
+			ruleConfig := newYAMLLintRuleConfig(yamllintRules[name].defaults)
+			ruleConfig.Level = level
+			linter.rules[name] = ruleConfig
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config to extend: %s", model.Extends)
+	}
+
+	switch ignore := model.Ignore.(type) {
+	case nil:
+	case string:
+		for _, pattern := range strings.Split(ignore, "\n") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" && !strings.HasPrefix(pattern, "#") {
+				linter.ignore = append(linter.ignore, pattern)
+			}
+		}
+	case []interface{}:
+		for _, pattern := range ignore {
+			linter.ignore = append(linter.ignore, fmt.Sprint(pattern))
+		}
+	default:
+		return nil, fmt.Errorf("invalid ignore: expected a string or a list")
+	}
+
+	for name, value := range model.Rules {
+		rule, ok := yamllintRules[name]
+		if !ok {
+			if value != "disable" {
+				linter.unsupportedRules[name] = yamllintRuleLine(configBytes, name)
+			}
+			continue
+		}
+
+		switch value := value.(type) {
+		case string:
+			switch value {
+			case "disable":
+				delete(linter.rules, name)
+			case "enable":
+				linter.rules[name] = newYAMLLintRuleConfig(rule.defaults)
+			default:
+				return nil, fmt.Errorf("rule %s: invalid value: %s", name, value)
+			}
+		case map[interface{}]interface{}:
+			ruleConfig, ok := linter.rules[name]
+			if !ok {
+				ruleConfig = newYAMLLintRuleConfig(rule.defaults)
+			}
+			for key, option := range value {
+				if key == "level" {
+					level := fmt.Sprint(option)
+					if level != severityError && level != severityWarning {
+						return nil, fmt.Errorf("rule %s: invalid level: %s", name, level)
+					}
+					ruleConfig.Level = level
+					continue
+				}
+				if _, ok := rule.defaults[fmt.Sprint(key)]; !ok {
+					return nil, fmt.Errorf("rule %s: unknown option: %s", name, key)
+				}
+				ruleConfig.Options[fmt.Sprint(key)] = option
+			}
+			linter.rules[name] = ruleConfig
+		default:
+			return nil, fmt.Errorf("rule %s: invalid config", name)
+		}
+	}
+
+	return linter, nil
+}
+
+// yamllintRuleLine returns the line of the rule's config, or 1 if it is not found.
+func yamllintRuleLine(configBytes []byte, name string) int {
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(configBytes, &document); err != nil || len(document.Content) == 0 {
+		return 1
+	}
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "rules" {
+			continue
+		}
+		rules := root.Content[i+1]
+		for j := 0; j+1 < len(rules.Content); j += 2 {
+			if rules.Content[j].Value == name {
+				return rules.Content[j].Line
+			}
+		}
+	}
+	return 1
+}
+
+func newYAMLLintRuleConfig(defaults map[string]interface{}) yamllintRuleConfig {
+	config := yamllintRuleConfig{Level: severityError, Options: map[string]interface{}{}}
+	for key, value := range defaults {
+		config.Options[key] = value
+	}
+	return config
+}
+
+func (c yamllintRuleConfig) intOption(key string) int {
+	value, _ := c.Options[key].(int)
+	return value
+}
+
+func (c yamllintRuleConfig) boolOption(key string) bool {
+	value, _ := c.Options[key].(bool)
+	return value
+}
+
+func (c yamllintRuleConfig) stringOption(key string) string {
+	return fmt.Sprint(c.Options[key])
+}
+
+func (c yamllintRuleConfig) stringsOption(key string) []string {
+	values, _ := c.Options[key].([]interface{})
+	var strs []string
+	for _, value := range values {
+		strs = append(strs, fmt.Sprint(value))
+	}
+	return strs
+}
+
+// readYAMLLintConfig reads the repo level yamllint config of the directory, falling back to the given config.
+func readYAMLLintConfig(dir, fallbackConfig string) (*yamlLinter, error) {
+	for _, name := range yamllintConfigFileNames {
+		pth := filepath.Join(dir, name)
+		configBytes, err := ioutil.ReadFile(pth)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		linter, err := parseYAMLLintConfig(configBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid yamllint config (%s): %w", pth, err)
+		}
+		linter.configFile = name
+		return linter, nil
+	}
+
+	linter, err := parseYAMLLintConfig([]byte(fallbackConfig))
+	if err != nil {
+		return nil, fmt.Errorf("invalid yamllint config: %w", err)
+	}
+	return linter, nil
+}
+
+// lintYAMLFiles lints the YAML files of the directory.
+func lintYAMLFiles(dir, fallbackConfig string) ([]diagnostic, error) {
+	linter, err := readYAMLLintConfig(dir, fallbackConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := linter.unsupportedRuleIssues()
+	if err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, pth)
+		if err != nil {
+			return err
+		}
+		if rel != "." && linter.isIgnored(filepath.ToSlash(rel), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !isYAMLFile(info.Name()) {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(pth)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, linter.lint(rel, string(content))...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// unsupportedRuleIssues reports the enabled rules, which are not implemented, instead of ignoring them silently.
+func (l *yamlLinter) unsupportedRuleIssues() []diagnostic {
+	var issues []diagnostic
+	for name, line := range l.unsupportedRules {
+		issues = append(issues, diagnostic{
+			Check:    checkYAMLLint,
+			Rule:     "unsupported-rule",
+			File:     l.configFile,
+			Line:     line,
+			Column:   1,
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("rule %s is not supported, its problems are not reported", name),
+			Fix:      "disable the rule, or lint the files with yamllint",
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+func isYAMLFile(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml") || name == ".yamllint"
+}
+
+// isIgnored matches the slash separated relative path against the gitignore style ignore patterns.
+func (l *yamlLinter) isIgnored(rel string, isDir bool) bool {
+	for _, pattern := range l.ignore {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "/") || strings.Contains(pattern, "/") {
+			if matched, _ := filepath.Match(strings.TrimPrefix(pattern, "/"), rel); matched {
+				return true
+			}
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, rel[strings.LastIndex(rel, "/")+1:]); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// lint runs the enabled rules on the file content. Problems are sorted by their location.
+func (l *yamlLinter) lint(file, content string) []diagnostic {
+	f := &yamllintFile{
+		path:    file,
+		content: content,
+		lines:   strings.Split(content, "\n"),
+	}
+
+	var issues []diagnostic
+	decoder := yamlv3.NewDecoder(strings.NewReader(content))
+	for {
+		var document yamlv3.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			break
+		} else if err != nil {
+			line := 1
+			if match := regexp.MustCompile(`line (\d+):`).FindStringSubmatch(err.Error()); match != nil {
+				line, _ = strconv.Atoi(match[1])
+			}
+			f.documents = nil
+			issues = append(issues, diagnostic{
+				Check:    checkYAMLLint,
+				Rule:     "syntax",
+				File:     file,
+				Line:     line,
+				Column:   1,
+				Severity: severityError,
+				Message:  fmt.Sprintf("syntax error: %s", err),
+			})
+			break
+		}
+		f.documents = append(f.documents, &document)
+	}
+
+	var names []string
+	for name := range l.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ruleConfig := l.rules[name]
+		rule := yamllintRules[name]
+		if rule.nodeBased && f.documents == nil {
+			continue
+		}
+		for _, problem := range rule.check(f, ruleConfig) {
+			issues = append(issues, diagnostic{
+				Check:    checkYAMLLint,
+				Rule:     name,
+				File:     file,
+				Line:     problem.Line,
+				Column:   problem.Column,
+				Severity: ruleConfig.Level,
+				Message:  problem.Message,
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// walkYAMLNodes calls fn for every node of the documents with its parent, in document order.
+func (f *yamllintFile) walkYAMLNodes(fn func(node, parent *yamlv3.Node)) {
+	var walk func(node, parent *yamlv3.Node)
+	walk = func(node, parent *yamlv3.Node) {
+		fn(node, parent)
+		for _, child := range node.Content {
+			walk(child, node)
+		}
+	}
+	for _, document := range f.documents {
+		walk(document, nil)
+	}
+}
@@ -0,0 +1,1029 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// yamllintRule is a Go implementation of a yamllint rule, with yamllint's option names and defaults.
+type yamllintRule struct {
+	description string
+	defaults    map[string]interface{}
+	// nodeBased rules need a parsed document, the others work on the lines of the file.
+	nodeBased bool
+	check     func(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem
+}
+
+var yamllintRules = map[string]yamllintRule{
+	"anchors": {
+		description: "Aliases must refer to declared anchors, anchors can be required to be unique and used",
+		defaults: map[string]interface{}{
+			"forbid-undeclared-aliases": true,
+			"forbid-duplicated-anchors": false,
+			"forbid-unused-anchors":     false,
+		},
+		check: checkYAMLAnchors,
+	},
+	"braces": {
+		description: "Spaces inside the braces of flow mappings",
+		defaults: map[string]interface{}{
+			"forbid":                  false,
+			"min-spaces-inside":       0,
+			"max-spaces-inside":       0,
+			"min-spaces-inside-empty": -1,
+			"max-spaces-inside-empty": -1,
+		},
+		nodeBased: true,
+		check:     checkYAMLBraces,
+	},
+	"brackets": {
+		description: "Spaces inside the brackets of flow sequences",
+		defaults: map[string]interface{}{
+			"forbid":                  false,
+			"min-spaces-inside":       0,
+			"max-spaces-inside":       0,
+			"min-spaces-inside-empty": -1,
+			"max-spaces-inside-empty": -1,
+		},
+		nodeBased: true,
+		check:     checkYAMLBrackets,
+	},
+	"colons": {
+		description: "Spaces before and after colons",
+		defaults:    map[string]interface{}{"max-spaces-before": 0, "max-spaces-after": 1},
+		nodeBased:   true,
+		check:       checkYAMLColons,
+	},
+	"commas": {
+		description: "Spaces before and after commas",
+		defaults:    map[string]interface{}{"max-spaces-before": 0, "min-spaces-after": 1, "max-spaces-after": 1},
+		nodeBased:   true,
+		check:       checkYAMLCommas,
+	},
+	"comments": {
+		description: "Spaces at the start of comments and before inline comments",
+		defaults: map[string]interface{}{
+			"require-starting-space":  true,
+			"ignore-shebangs":         true,
+			"min-spaces-from-content": 2,
+		},
+		check: checkYAMLComments,
+	},
+	"comments-indentation": {
+		description: "Comments must be indented like the content",
+		defaults:    map[string]interface{}{},
+		check:       checkYAMLCommentsIndentation,
+	},
+	"document-start": {
+		description: "Documents must start (or must not start) with ---",
+		defaults:    map[string]interface{}{"present": true},
+		check:       checkYAMLDocumentStart,
+	},
+	"empty-lines": {
+		description: "Maximum number of consecutive blank lines",
+		defaults:    map[string]interface{}{"max": 2, "max-start": 0, "max-end": 0},
+		check:       checkYAMLEmptyLines,
+	},
+	"hyphens": {
+		description: "Spaces after the hyphens of block sequences",
+		defaults:    map[string]interface{}{"max-spaces-after": 1},
+		nodeBased:   true,
+		check:       checkYAMLHyphens,
+	},
+	"indentation": {
+		description: "Consistent indentation of mappings and sequences",
+		defaults: map[string]interface{}{
+			"spaces":                   "consistent",
+			"indent-sequences":         true,
+			"check-multi-line-strings": false,
+		},
+		nodeBased: true,
+		check:     checkYAMLIndentation,
+	},
+	"key-duplicates": {
+		description: "Keys must be unique within a mapping",
+		defaults:    map[string]interface{}{},
+		nodeBased:   true,
+		check:       checkYAMLKeyDuplicates,
+	},
+	"line-length": {
+		description: "Maximum length of the lines",
+		defaults: map[string]interface{}{
+			"max":                                 80,
+			"allow-non-breakable-words":           true,
+			"allow-non-breakable-inline-mappings": false,
+		},
+		check: checkYAMLLineLength,
+	},
+	"new-line-at-end-of-file": {
+		description: "Files must end with a new line character",
+		defaults:    map[string]interface{}{},
+		check:       checkYAMLNewLineAtEndOfFile,
+	},
+	"new-lines": {
+		description: "Type of the new line characters",
+		defaults:    map[string]interface{}{"type": "unix"},
+		check:       checkYAMLNewLines,
+	},
+	"octal-values": {
+		description: "Octal values, which YAML 1.1 and 1.2 resolve differently, are forbidden",
+		defaults:    map[string]interface{}{"forbid-implicit-octal": true, "forbid-explicit-octal": true},
+		nodeBased:   true,
+		check:       checkYAMLOctalValues,
+	},
+	"quoted-strings": {
+		description: "Quoting style of string values",
+		defaults:    map[string]interface{}{"quote-type": "any", "required": true},
+		nodeBased:   true,
+		check:       checkYAMLQuotedStrings,
+	},
+	"trailing-spaces": {
+		description: "Lines must not end with spaces",
+		defaults:    map[string]interface{}{},
+		check:       checkYAMLTrailingSpaces,
+	},
+	"truthy": {
+		description: "Only the allowed boolean values can be used as plain scalars",
+		defaults:    map[string]interface{}{"allowed-values": []interface{}{"true", "false"}, "check-keys": true},
+		nodeBased:   true,
+		check:       checkYAMLTruthy,
+	},
+}
+
+// yamllintDefaultPreset lists the rules enabled by `extends: default`, with their levels.
+var yamllintDefaultPreset = map[string]string{
+	"anchors":                 severityError,
+	"braces":                  severityError,
+	"brackets":                severityError,
+	"colons":                  severityError,
+	"commas":                  severityError,
+	"comments":                severityWarning,
+	"comments-indentation":    severityWarning,
+	"document-start":          severityWarning,
+	"empty-lines":             severityError,
+	"hyphens":                 severityError,
+	"indentation":             severityError,
+	"key-duplicates":          severityError,
+	"line-length":             severityError,
+	"new-line-at-end-of-file": severityError,
+	"new-lines":               severityError,
+	"trailing-spaces":         severityError,
+	"truthy":                  severityWarning,
+}
+
+// yaml11ImplicitRegexes match the plain scalars, which YAML 1.1 (used by yamllint) resolves to a non-string type.
+var yaml11ImplicitRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:yes|Yes|YES|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF)$`),
+	regexp.MustCompile(`^(?:[-+]?0b[0-1_]+|[-+]?0[0-7_]+|[-+]?(?:0|[1-9][0-9_]*)|[-+]?0x[0-9a-fA-F_]+|[-+]?[1-9][0-9_]*(?::[0-5]?[0-9])+)$`),
+	regexp.MustCompile(`^(?:[-+]?(?:[0-9][0-9_]*)\.[0-9_]*(?:[eE][-+][0-9]+)?|\.[0-9_]+(?:[eE][-+][0-9]+)?|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN))$`),
+	regexp.MustCompile(`^(?:~|null|Null|NULL|<<|=)$`),
+	regexp.MustCompile(`^(?:[0-9]{4}-[0-9]{2}-[0-9]{2}|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt]|[ \t]+)[0-9]{1,2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]*)?(?:[ \t]*(?:Z|[-+][0-9]{1,2}(?::[0-9]{2})?))?)$`),
+}
+
+func checkYAMLTrailingSpaces(f *yamllintFile, _ yamllintRuleConfig) []yamllintProblem {
+	var problems []yamllintProblem
+	for i, line := range f.lines {
+		line = strings.TrimSuffix(line, "\r")
+		if trimmed := strings.TrimRight(line, " \t"); trimmed != line {
+			problems = append(problems, yamllintProblem{Line: i + 1, Column: len(trimmed) + 1, Message: "trailing spaces"})
+		}
+	}
+	return problems
+}
+
+func checkYAMLNewLineAtEndOfFile(f *yamllintFile, _ yamllintRuleConfig) []yamllintProblem {
+	if f.content == "" || strings.HasSuffix(f.content, "\n") {
+		return nil
+	}
+	last := f.lines[len(f.lines)-1]
+	return []yamllintProblem{{Line: len(f.lines), Column: len(last) + 1, Message: "no new line character at the end of file"}}
+}
+
+func checkYAMLEmptyLines(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	lines := f.lines
+	// The content after the last line break is not a line
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var problems []yamllintProblem
+	report := func(end, count, max int) {
+		if count > max {
+			problems = append(problems, yamllintProblem{Line: end, Column: 1, Message: fmt.Sprintf("too many blank lines (%d > %d)", count, max)})
+		}
+	}
+
+	blank := 0
+	for i, line := range lines {
+		if strings.TrimSuffix(line, "\r") == "" {
+			blank++
+			continue
+		}
+		if blank > 0 {
+			if blank == i {
+				report(i, blank, config.intOption("max-start"))
+			} else {
+				report(i, blank, config.intOption("max"))
+			}
+		}
+		blank = 0
+	}
+	if blank > 0 {
+		max := config.intOption("max-end")
+		if blank == len(lines) {
+			max = config.intOption("max-start")
+		}
+		report(len(lines), blank, max)
+	}
+
+	return problems
+}
+
+// yamllintScan is the content and the comments of the lines of a file, see contentLines.
+type yamllintScan struct {
+	content  []string
+	comments []yamllintComment
+}
+
+type yamllintComment struct {
+	Line   int
+	Column int
+	Text   string
+	// Inline comments follow content on the same line, with SpacesBefore spaces in between.
+	Inline       bool
+	SpacesBefore int
+}
+
+// comments returns the comments of the file, outside of block scalars and quoted strings.
+func (f *yamllintFile) comments() []yamllintComment {
+	f.scan()
+	return f.scanned.comments
+}
+
+// contentLines returns the lines of the file without comments and trailing spaces, the characters of quoted strings
+// replaced by x (keeping the quotes), and the lines of block scalars emptied. Indicators (like : or -) are only found
+// outside of scalars in these lines.
+func (f *yamllintFile) contentLines() []string {
+	f.scan()
+	return f.scanned.content
+}
+
+// scan splits the lines of the file into content and comments, skipping the content of block scalars.
+func (f *yamllintFile) scan() {
+	if f.scanned != nil {
+		return
+	}
+
+	blockLines := map[int]bool{}
+	f.walkYAMLNodes(func(node, _ *yamlv3.Node) {
+		if node.Kind != yamlv3.ScalarNode || node.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) == 0 {
+			return
+		}
+		indicatorLine := f.lines[node.Line-1]
+		parentIndent := len(indicatorLine) - len(strings.TrimLeft(indicatorLine, " "))
+		contentIndent := -1
+		for i := node.Line; i < len(f.lines); i++ {
+			line := strings.TrimRight(f.lines[i], " \t\r")
+			if line == "" {
+				blockLines[i+1] = true
+				continue
+			}
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			if contentIndent == -1 {
+				contentIndent = indent
+			}
+			if indent < contentIndent || contentIndent <= parentIndent {
+				break
+			}
+			blockLines[i+1] = true
+		}
+	})
+
+	scanned := &yamllintScan{content: make([]string, len(f.lines))}
+	inSingle, inDouble := false, false
+	for i, line := range f.lines {
+		if blockLines[i+1] {
+			continue
+		}
+		line = strings.TrimSuffix(line, "\r")
+
+		content := []byte(line)
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case inDouble:
+				if c == '\\' && j+1 < len(line) {
+					content[j] = 'x'
+					j++
+					content[j] = 'x'
+				} else if c == '"' {
+					inDouble = false
+				} else {
+					content[j] = 'x'
+				}
+			case inSingle:
+				if c == '\'' && j+1 < len(line) && line[j+1] == '\'' {
+					content[j] = 'x'
+					j++
+					content[j] = 'x'
+				} else if c == '\'' {
+					inSingle = false
+				} else {
+					content[j] = 'x'
+				}
+			case c == '#' && (j == 0 || line[j-1] == ' ' || line[j-1] == '\t'):
+				code := strings.TrimRight(line[:j], " \t")
+				scanned.comments = append(scanned.comments, yamllintComment{
+					Line:         i + 1,
+					Column:       j + 1,
+					Text:         line[j:],
+					Inline:       code != "",
+					SpacesBefore: j - len(code),
+				})
+				content = content[:j]
+				j = len(line)
+			case (c == '"' || c == '\'') && startsYAMLToken(line[:j]):
+				inDouble, inSingle = c == '"', c == '\''
+			}
+		}
+		scanned.content[i] = strings.TrimRight(string(content), " \t")
+	}
+
+	f.scanned = scanned
+}
+
+// startsYAMLToken tells if a token (like a quoted string) can start after the given line prefix.
+func startsYAMLToken(prefix string) bool {
+	if prefix != "" && strings.ContainsAny(prefix[len(prefix)-1:], "[{,") {
+		return true
+	}
+	trimmed := strings.TrimRight(prefix, " \t")
+	if trimmed == "" {
+		return true
+	}
+	return trimmed != prefix && strings.ContainsAny(trimmed[len(trimmed)-1:], ":-[{,?")
+}
+
+func checkYAMLComments(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	var problems []yamllintProblem
+	for _, comment := range f.comments() {
+		if minSpaces := config.intOption("min-spaces-from-content"); comment.Inline && minSpaces != -1 && comment.SpacesBefore < minSpaces {
+			problems = append(problems, yamllintProblem{Line: comment.Line, Column: comment.Column, Message: "too few spaces before comment"})
+		}
+
+		if !config.boolOption("require-starting-space") {
+			continue
+		}
+		text := strings.TrimLeft(comment.Text, "#")
+		if text == "" || text[0] == ' ' {
+			continue
+		}
+		if config.boolOption("ignore-shebangs") && comment.Line == 1 && comment.Column == 1 && regexp.MustCompile(`^!\S`).MatchString(text) {
+			continue
+		}
+		problems = append(problems, yamllintProblem{Line: comment.Line, Column: comment.Column + len(comment.Text) - len(text), Message: "missing starting space in comment"})
+	}
+	return problems
+}
+
+func checkYAMLKeyDuplicates(f *yamllintFile, _ yamllintRuleConfig) []yamllintProblem {
+	var problems []yamllintProblem
+	f.walkYAMLNodes(func(node, _ *yamlv3.Node) {
+		if node.Kind != yamlv3.MappingNode {
+			return
+		}
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yamlv3.ScalarNode || key.Value == "<<" {
+				continue
+			}
+			if seen[key.Value] {
+				problems = append(problems, yamllintProblem{Line: key.Line, Column: key.Column, Message: fmt.Sprintf("duplication of key %q in mapping", key.Value)})
+			}
+			seen[key.Value] = true
+		}
+	})
+	return problems
+}
+
+func checkYAMLOctalValues(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	implicit := regexp.MustCompile(`^0[0-7]+$`)
+	explicit := regexp.MustCompile(`^0o[0-7]+$`)
+
+	var problems []yamllintProblem
+	f.walkYAMLNodes(func(node, _ *yamlv3.Node) {
+		if node.Kind != yamlv3.ScalarNode || node.Style != 0 {
+			return
+		}
+		if config.boolOption("forbid-implicit-octal") && implicit.MatchString(node.Value) {
+			problems = append(problems, yamllintProblem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("forbidden implicit octal value %q", node.Value)})
+		}
+		if config.boolOption("forbid-explicit-octal") && explicit.MatchString(node.Value) {
+			problems = append(problems, yamllintProblem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("forbidden explicit octal value %q", node.Value)})
+		}
+	})
+	return problems
+}
+
+func checkYAMLQuotedStrings(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	quoteType := config.stringOption("quote-type")
+	required := config.stringOption("required")
+
+	var problems []yamllintProblem
+	var walk func(node *yamlv3.Node, isKey, inFlow bool)
+	walk = func(node *yamlv3.Node, isKey, inFlow bool) {
+		switch node.Kind {
+		case yamlv3.DocumentNode, yamlv3.SequenceNode:
+			for _, child := range node.Content {
+				walk(child, false, inFlow || node.Style&yamlv3.FlowStyle != 0)
+			}
+			return
+		case yamlv3.MappingNode:
+			for i, child := range node.Content {
+				walk(child, i%2 == 0, inFlow || node.Style&yamlv3.FlowStyle != 0)
+			}
+			return
+		case yamlv3.ScalarNode:
+		default:
+			return
+		}
+		// Keys and block scalars are not checked
+		if isKey || node.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 {
+			return
+		}
+
+		quoted := node.Style&(yamlv3.DoubleQuotedStyle|yamlv3.SingleQuotedStyle) != 0
+		if !quoted && isYAML11Implicit(node.Value) {
+			return
+		}
+		quoteMatches := quoteType == "any" ||
+			(quoteType == "double" && node.Style&yamlv3.DoubleQuotedStyle != 0) ||
+			(quoteType == "single" && node.Style&yamlv3.SingleQuotedStyle != 0)
+
+		var message string
+		switch {
+		case required == "true" && !quoted:
+			message = "string value is not quoted"
+		case required == "only-when-needed" && quoted && node.Value != "" && !isYAML11Implicit(node.Value) && !yamlQuotesNeeded(node.Value, inFlow):
+			message = fmt.Sprintf("string value is redundantly quoted with %s quotes", quoteTypeName(node))
+		case quoted && !quoteMatches:
+			message = fmt.Sprintf("string value is not quoted with %s quotes", quoteType)
+		}
+		if message != "" {
+			problems = append(problems, yamllintProblem{Line: node.Line, Column: node.Column, Message: message})
+		}
+	}
+	for _, document := range f.documents {
+		walk(document, false, false)
+	}
+	return problems
+}
+
+func quoteTypeName(node *yamlv3.Node) string {
+	if node.Style&yamlv3.SingleQuotedStyle != 0 {
+		return "single"
+	}
+	return "double"
+}
+
+func isYAML11Implicit(value string) bool {
+	for _, re := range yaml11ImplicitRegexes {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlQuotesNeeded tells if the string would be parsed differently without quotes.
+func yamlQuotesNeeded(value string, inFlow bool) bool {
+	if inFlow && strings.ContainsAny(value, ",[]{}") {
+		return true
+	}
+
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte("key: "+value), &document); err != nil || len(document.Content) != 1 {
+		return true
+	}
+	mapping := document.Content[0]
+	if mapping.Kind != yamlv3.MappingNode || len(mapping.Content) != 2 {
+		return true
+	}
+	parsed := mapping.Content[1]
+	return parsed.Kind != yamlv3.ScalarNode || parsed.Style != 0 || parsed.Value != value
+}
+
+// yamllintIndicator is an indicator character (like : or -) of the YAML syntax, found in the content lines.
+type yamllintIndicator struct {
+	Line  int
+	Index int
+	Char  byte
+}
+
+// indicators finds the flow collection brackets and the ',' ':' '-' '?' indicators in the content lines.
+// Plain scalars (like URLs) contain these characters too, they only count as indicators in the positions, where
+// YAML parses them as such.
+func (f *yamllintFile) indicators() []yamllintIndicator {
+	var indicators []yamllintIndicator
+	depth := 0
+	for i, line := range f.contentLines() {
+		// blockIndicators tells if only block indicators (like in "- - a") precede the current character
+		blockIndicators := true
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			if c == ' ' || c == '\t' {
+				continue
+			}
+			followedBySpace := j+1 == len(line) || line[j+1] == ' ' || line[j+1] == '\t'
+
+			isIndicator := false
+			switch c {
+			case '[', '{':
+				if isIndicator = depth > 0 || startsYAMLToken(line[:j]); isIndicator {
+					depth++
+				}
+			case ']', '}':
+				if isIndicator = depth > 0; isIndicator {
+					depth--
+				}
+			case ',':
+				isIndicator = depth > 0
+			case ':':
+				isIndicator = followedBySpace ||
+					depth > 0 && (strings.IndexByte(",]}", line[j+1]) != -1 || j > 0 && (line[j-1] == '"' || line[j-1] == '\''))
+			case '-', '?':
+				isIndicator = depth == 0 && blockIndicators && followedBySpace
+			}
+
+			if isIndicator {
+				indicators = append(indicators, yamllintIndicator{Line: i + 1, Index: j, Char: c})
+			}
+			if !isIndicator || (c != '-' && c != '?') {
+				blockIndicators = false
+			}
+		}
+	}
+	return indicators
+}
+
+// spacesAfter counts the spaces after the indicator, and returns the index of the next token.
+// The spaces are not counted (ok is false), if the next token is on a later line.
+func (f *yamllintFile) spacesAfter(indicator yamllintIndicator) (spaces, next int, ok bool) {
+	line := f.contentLines()[indicator.Line-1]
+	next = indicator.Index + 1
+	for next < len(line) && line[next] == ' ' {
+		next++
+	}
+	return next - indicator.Index - 1, next, next < len(line)
+}
+
+// spacesBefore counts the spaces before the indicator.
+// The spaces are not counted (ok is false), if the previous token is on an earlier line.
+func (f *yamllintFile) spacesBefore(indicator yamllintIndicator) (spaces int, ok bool) {
+	line := f.contentLines()[indicator.Line-1]
+	prev := indicator.Index - 1
+	for prev >= 0 && line[prev] == ' ' {
+		prev--
+	}
+	return indicator.Index - prev - 1, prev >= 0
+}
+
+// onlySpacesBetween tells if there is nothing but whitespace between the indicators.
+func (f *yamllintFile) onlySpacesBetween(from, to yamllintIndicator) bool {
+	lines := f.contentLines()
+	for line := from.Line; line <= to.Line; line++ {
+		content := lines[line-1]
+		if line == to.Line {
+			content = content[:to.Index]
+		}
+		if line == from.Line && from.Index < len(content) {
+			content = content[from.Index+1:]
+		}
+		if strings.TrimSpace(content) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// yamllintSpacesProblem checks the number of spaces before the token at the index, like yamllint reports it:
+// too many spaces at the last space, too few spaces at the token.
+func yamllintSpacesProblem(line, index, spaces, min, max int, subject string) []yamllintProblem {
+	switch {
+	case max != -1 && spaces > max:
+		return []yamllintProblem{{Line: line, Column: index, Message: "too many spaces " + subject}}
+	case min != -1 && spaces < min:
+		return []yamllintProblem{{Line: line, Column: index + 1, Message: "too few spaces " + subject}}
+	}
+	return nil
+}
+
+func checkYAMLBraces(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	return checkYAMLFlowCollections(f, config, '{', '}', "braces", "flow mapping")
+}
+
+func checkYAMLBrackets(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	return checkYAMLFlowCollections(f, config, '[', ']', "brackets", "flow sequence")
+}
+
+// checkYAMLFlowCollections checks the spaces inside the open and close characters of flow collections,
+// for the braces and brackets rules.
+func checkYAMLFlowCollections(f *yamllintFile, config yamllintRuleConfig, open, close byte, name, collection string) []yamllintProblem {
+	min, max := config.intOption("min-spaces-inside"), config.intOption("max-spaces-inside")
+	minEmpty, maxEmpty := config.intOption("min-spaces-inside-empty"), config.intOption("max-spaces-inside-empty")
+	if minEmpty == -1 {
+		minEmpty = min
+	}
+	if maxEmpty == -1 {
+		maxEmpty = max
+	}
+	forbid := config.stringOption("forbid")
+
+	var problems []yamllintProblem
+	indicators := f.indicators()
+	for i, indicator := range indicators {
+		switch indicator.Char {
+		case open:
+			empty := i+1 < len(indicators) && indicators[i+1].Char == close && f.onlySpacesBetween(indicator, indicators[i+1])
+			if forbid == "true" || (forbid == "non-empty" && !empty) {
+				problems = append(problems, yamllintProblem{Line: indicator.Line, Column: indicator.Index + 2, Message: "forbidden " + collection})
+				continue
+			}
+
+			spaces, next, ok := f.spacesAfter(indicator)
+			if !ok {
+				continue
+			}
+			if empty {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, minEmpty, maxEmpty, "inside empty "+name)...)
+			} else {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, min, max, "inside "+name)...)
+			}
+		case close:
+			if forbid == "true" || (i > 0 && indicators[i-1].Char == open && f.onlySpacesBetween(indicators[i-1], indicator)) {
+				continue
+			}
+			if spaces, ok := f.spacesBefore(indicator); ok {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, indicator.Index, spaces, min, max, "inside "+name)...)
+			}
+		}
+	}
+	return problems
+}
+
+func checkYAMLColons(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	var problems []yamllintProblem
+	for _, indicator := range f.indicators() {
+		switch indicator.Char {
+		case ':':
+			if spaces, ok := f.spacesBefore(indicator); ok && !(spaces == 1 && precedesYAMLAlias(f.contentLines()[indicator.Line-1][:indicator.Index-1])) {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, indicator.Index, spaces, -1, config.intOption("max-spaces-before"), "before colon")...)
+			}
+			if spaces, next, ok := f.spacesAfter(indicator); ok {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, -1, config.intOption("max-spaces-after"), "after colon")...)
+			}
+		case '?':
+			if spaces, next, ok := f.spacesAfter(indicator); ok {
+				problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, -1, config.intOption("max-spaces-after"), "after question mark")...)
+			}
+		}
+	}
+	return problems
+}
+
+// precedesYAMLAlias tells if the line prefix ends with an alias, which needs a space before the colon (like "*a : b").
+func precedesYAMLAlias(prefix string) bool {
+	start := strings.LastIndexAny(prefix, " \t[{,") + 1
+	return strings.HasPrefix(prefix[start:], "*")
+}
+
+func checkYAMLCommas(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	maxBefore := config.intOption("max-spaces-before")
+
+	var problems []yamllintProblem
+	for _, indicator := range f.indicators() {
+		if indicator.Char != ',' {
+			continue
+		}
+		if spaces, ok := f.spacesBefore(indicator); ok {
+			problems = append(problems, yamllintSpacesProblem(indicator.Line, indicator.Index, spaces, -1, maxBefore, "before comma")...)
+		} else if maxBefore != -1 {
+			// The comma starts the line
+			column := indicator.Index
+			if column == 0 {
+				column = 1
+			}
+			problems = append(problems, yamllintProblem{Line: indicator.Line, Column: column, Message: "too many spaces before comma"})
+		}
+		if spaces, next, ok := f.spacesAfter(indicator); ok {
+			problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, config.intOption("min-spaces-after"), config.intOption("max-spaces-after"), "after comma")...)
+		}
+	}
+	return problems
+}
+
+func checkYAMLHyphens(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	var problems []yamllintProblem
+	for _, indicator := range f.indicators() {
+		if indicator.Char != '-' {
+			continue
+		}
+		if spaces, next, ok := f.spacesAfter(indicator); ok {
+			problems = append(problems, yamllintSpacesProblem(indicator.Line, next, spaces, -1, config.intOption("max-spaces-after"), "after hyphen")...)
+		}
+	}
+	return problems
+}
+
+// checkYAMLNewLines checks the first line break of the file, like yamllint does.
+func checkYAMLNewLines(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	expected := "\n"
+	if newLineType := config.stringOption("type"); newLineType == "dos" || (newLineType == "platform" && runtime.GOOS == "windows") {
+		expected = "\r\n"
+	}
+	if len(f.lines) < 2 {
+		return nil
+	}
+
+	first := f.lines[0] + "\n"
+	if strings.HasSuffix(first, expected) && (expected == "\r\n" || !strings.HasSuffix(first, "\r\n")) {
+		return nil
+	}
+	escaped := strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(expected)
+	return []yamllintProblem{{Line: 1, Column: len(strings.TrimSuffix(f.lines[0], "\r")) + 1, Message: "wrong new line character: expected " + escaped}}
+}
+
+func checkYAMLLineLength(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	max := config.intOption("max")
+	allowInlineMappings := config.boolOption("allow-non-breakable-inline-mappings")
+	allowWords := config.boolOption("allow-non-breakable-words") || allowInlineMappings
+
+	var problems []yamllintProblem
+	for i, line := range f.lines {
+		line = strings.TrimSuffix(line, "\r")
+		length := utf8.RuneCountInString(line)
+		if length <= max {
+			continue
+		}
+
+		if content := strings.TrimLeft(line, " "); allowWords && content != "" {
+			switch {
+			case content[0] == '#':
+				content = strings.TrimLeft(content, "#")
+			case content[0] == '-':
+				content = strings.TrimPrefix(content[1:], " ")
+			}
+			if !strings.Contains(strings.TrimPrefix(content, " "), " ") {
+				continue
+			}
+			// Like "key: https://example.com/long/url"
+			if colon := strings.Index(content, ": "); allowInlineMappings && colon != -1 && !strings.Contains(strings.TrimLeft(content[colon+2:], " "), " ") {
+				continue
+			}
+		}
+		problems = append(problems, yamllintProblem{Line: i + 1, Column: max + 1, Message: fmt.Sprintf("line too long (%d > %d characters)", length, max)})
+	}
+	return problems
+}
+
+func checkYAMLDocumentStart(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	present := config.boolOption("present")
+
+	var problems []yamllintProblem
+	// expectStart is set at the start of the stream, and after directives and document end markers
+	expectStart := true
+	for i, line := range f.contentLines() {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "%"):
+			expectStart = true
+		case line == "---" || strings.HasPrefix(line, "--- "):
+			if !present {
+				problems = append(problems, yamllintProblem{Line: i + 1, Column: 1, Message: `found forbidden document start "---"`})
+			}
+			expectStart = false
+		case line == "..." || strings.HasPrefix(line, "... "):
+			expectStart = true
+		default:
+			if expectStart && present {
+				problems = append(problems, yamllintProblem{Line: i + 1, Column: 1, Message: `missing document start "---"`})
+			}
+			expectStart = false
+		}
+	}
+	return problems
+}
+
+// yamllintTruthyValues are the plain scalars, which YAML 1.1 resolves to booleans.
+var yamllintTruthyValues = []string{"YES", "Yes", "yes", "NO", "No", "no", "TRUE", "True", "true", "FALSE", "False", "false", "ON", "On", "on", "OFF", "Off", "off"}
+
+func checkYAMLTruthy(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	allowed := config.stringsOption("allowed-values")
+	sort.Strings(allowed)
+	message := fmt.Sprintf("truthy value should be one of [%s]", strings.Join(allowed, ", "))
+
+	keys := map[*yamlv3.Node]bool{}
+	var problems []yamllintProblem
+	f.walkYAMLNodes(func(node, parent *yamlv3.Node) {
+		if node.Kind == yamlv3.MappingNode {
+			for i := 0; i < len(node.Content); i += 2 {
+				keys[node.Content[i]] = true
+			}
+		}
+		// Quoted and explicitly tagged scalars are strings
+		if node.Kind != yamlv3.ScalarNode || node.Style != 0 || (keys[node] && !config.boolOption("check-keys")) {
+			return
+		}
+		if sliceutil.IsStringInSlice(node.Value, yamllintTruthyValues) && !sliceutil.IsStringInSlice(node.Value, allowed) {
+			problems = append(problems, yamllintProblem{Line: node.Line, Column: node.Column, Message: message})
+		}
+	})
+	return problems
+}
+
+// checkYAMLCommentsIndentation checks, that the comments on their own lines are indented like the content before
+// or after them.
+func checkYAMLCommentsIndentation(f *yamllintFile, _ yamllintRuleConfig) []yamllintProblem {
+	lines := f.contentLines()
+	indent := func(line string) int {
+		return len(line) - len(strings.TrimLeft(line, " "))
+	}
+
+	var problems []yamllintProblem
+	var previous *yamllintComment
+	for i, comment := range f.comments() {
+		before := previous
+		previous = &f.comments()[i]
+		if comment.Inline {
+			continue
+		}
+
+		prevIndent, prevLine := 0, comment.Line-1
+		for ; prevLine > 0 && lines[prevLine-1] == ""; prevLine-- {
+		}
+		if prevLine > 0 {
+			prevIndent = indent(lines[prevLine-1])
+		}
+		nextIndent := 0
+		for _, line := range lines[comment.Line:] {
+			if line != "" {
+				nextIndent = indent(line)
+				break
+			}
+		}
+
+		// Only the indentation of the next line is valid, if it is deeper
+		if prevIndent <= nextIndent {
+			prevIndent = nextIndent
+		}
+		// Once a comment went back to an indentation, the following ones need to follow it
+		if before != nil && !before.Inline && before.Line > prevLine {
+			prevIndent = before.Column - 1
+		}
+
+		if comment.Column-1 != prevIndent && comment.Column-1 != nextIndent {
+			problems = append(problems, yamllintProblem{Line: comment.Line, Column: comment.Column, Message: "comment not indented like content"})
+		}
+	}
+	return problems
+}
+
+// checkYAMLAnchors checks the anchors and aliases of each document. It works on the lines, as the parser rejects
+// the undeclared aliases.
+func checkYAMLAnchors(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	type anchor struct {
+		line, column int
+		used         bool
+	}
+
+	var problems []yamllintProblem
+	var names []string
+	anchors := map[string]*anchor{}
+	endDocument := func() {
+		for _, name := range names {
+			if a := anchors[name]; config.boolOption("forbid-unused-anchors") && !a.used {
+				problems = append(problems, yamllintProblem{Line: a.line, Column: a.column, Message: fmt.Sprintf("found unused anchor %q", name)})
+			}
+		}
+		names, anchors = nil, map[string]*anchor{}
+	}
+
+	for i, line := range f.contentLines() {
+		if line == "---" || strings.HasPrefix(line, "--- ") || line == "..." || strings.HasPrefix(line, "... ") {
+			endDocument()
+		}
+		for j := 0; j < len(line); j++ {
+			if (line[j] != '&' && line[j] != '*') || !startsYAMLToken(line[:j]) {
+				continue
+			}
+			end := j + 1
+			for end < len(line) && strings.IndexByte(" \t,[]{}", line[end]) == -1 {
+				end++
+			}
+			name := line[j+1 : end]
+
+			if line[j] == '*' {
+				if a, ok := anchors[name]; ok {
+					a.used = true
+				} else if config.boolOption("forbid-undeclared-aliases") {
+					problems = append(problems, yamllintProblem{Line: i + 1, Column: j + 1, Message: fmt.Sprintf("found undeclared alias %q", name)})
+				}
+			} else {
+				if _, ok := anchors[name]; ok && config.boolOption("forbid-duplicated-anchors") {
+					problems = append(problems, yamllintProblem{Line: i + 1, Column: j + 1, Message: fmt.Sprintf("found duplicated anchor %q", name)})
+				}
+				if _, ok := anchors[name]; !ok {
+					names = append(names, name)
+				}
+				anchors[name] = &anchor{line: i + 1, column: j + 1}
+			}
+			j = end
+		}
+	}
+	endDocument()
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// checkYAMLIndentation checks the indentation of the block collections and scalars starting on a new line.
+func checkYAMLIndentation(f *yamllintFile, config yamllintRuleConfig) []yamllintProblem {
+	indent := 0
+	if spaces, ok := config.Options["spaces"].(int); ok {
+		indent = spaces
+	}
+	indentSequences := config.stringOption("indent-sequences")
+
+	var problems []yamllintProblem
+	report := func(node *yamlv3.Node, expected ...int) {
+		for _, e := range expected {
+			if node.Column-1 == e {
+				return
+			}
+		}
+		problems = append(problems, yamllintProblem{
+			Line:    node.Line,
+			Column:  node.Column,
+			Message: fmt.Sprintf("wrong indentation: expected %d but found %d", expected[0], node.Column-1),
+		})
+	}
+	// indented returns the expected column of a nested node, detecting the indentation from the first one if needed
+	indented := func(parentColumn int, node *yamlv3.Node) int {
+		if indent == 0 && node.Column > parentColumn {
+			indent = node.Column - parentColumn
+		}
+		return parentColumn - 1 + indent
+	}
+
+	var walk func(node *yamlv3.Node)
+	walk = func(node *yamlv3.Node) {
+		if node.Kind == yamlv3.MappingNode && node.Style&yamlv3.FlowStyle == 0 {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if value.Line <= key.Line || value.Kind == yamlv3.AliasNode {
+					continue
+				}
+
+				if value.Kind == yamlv3.SequenceNode && value.Style&yamlv3.FlowStyle == 0 {
+					if indentSequences == "consistent" {
+						// The first sequence decides
+						indentSequences = fmt.Sprint(value.Column != key.Column)
+					}
+					switch indentSequences {
+					case "false":
+						report(value, key.Column-1)
+					case "whatever":
+						report(value, key.Column-1, indented(key.Column, value))
+					default:
+						report(value, indented(key.Column, value))
+					}
+					continue
+				}
+				report(value, indented(key.Column, value))
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+
+	for _, document := range f.documents {
+		if len(document.Content) == 0 {
+			continue
+		}
+		root := document.Content[0]
+		if root.Kind == yamlv3.MappingNode || root.Kind == yamlv3.SequenceNode {
+			report(root, 0)
+		}
+		walk(root)
+	}
+	return problems
+}
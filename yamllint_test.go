@@ -0,0 +1,264 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_yamlLinter_lint(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		content string
+		want    []string
+	}{
+		{
+			"Embedded config, valid file",
+			yamllintConfig,
+			`# Comment
+workflows:
+  test:
+    steps:
+    - script@1:
+        inputs:
+        - content: |-
+            #!/bin/bash
+            echo "it's #1"  # not a comment
+        - mode: "yes"
+        - path: "$HOME/path: with colon"
+    envs: { KEY: value }
+`,
+			nil,
+		},
+		{
+			"Indentation with indentless sequences",
+			yamllintConfig,
+			`workflows:
+    test:
+        steps:
+          - script: {}
+list:
+- a
+`,
+			[]string{
+				"test.yml:4:11: error: wrong indentation: expected 8 but found 10 (indentation)",
+				"test.yml:4:22: error: too few spaces inside empty braces (braces)",
+			},
+		},
+		{
+			"Quoted strings",
+			yamllintConfig,
+			`a: "value"
+b: 'single: quoted'
+c: "on"
+d: ""
+e: "#hash"
+f: '012'
+`,
+			[]string{
+				"test.yml:1:4: error: string value is redundantly quoted with double quotes (quoted-strings)",
+				"test.yml:2:4: error: string value is not quoted with double quotes (quoted-strings)",
+				"test.yml:6:4: error: string value is not quoted with double quotes (quoted-strings)",
+			},
+		},
+		{
+			"Braces, empty lines and comments",
+			yamllintConfig,
+			`
+
+a: {b: 1}
+c: {  d: 1 }
+
+
+#comment
+e: 1 # comment
+`,
+			[]string{
+				"test.yml:2:1: error: too many blank lines (2 > 0) (empty-lines)",
+				"test.yml:3:5: error: too few spaces inside braces (braces)",
+				"test.yml:3:9: error: too few spaces inside braces (braces)",
+				"test.yml:4:6: error: too many spaces inside braces (braces)",
+				"test.yml:6:1: error: too many blank lines (2 > 1) (empty-lines)",
+				"test.yml:7:2: error: missing starting space in comment (comments)",
+				"test.yml:8:6: error: too few spaces before comment (comments)",
+			},
+		},
+		{
+			"Octal values, duplicate keys, trailing spaces and missing new line",
+			yamllintConfig,
+			"mode: 0755 \nmode: 0o644\nkey: value",
+			[]string{
+				"test.yml:1:7: error: forbidden implicit octal value \"0755\" (octal-values)",
+				"test.yml:1:11: error: trailing spaces (trailing-spaces)",
+				"test.yml:2:1: error: duplication of key \"mode\" in mapping (key-duplicates)",
+				"test.yml:2:7: error: forbidden explicit octal value \"0o644\" (octal-values)",
+				"test.yml:3:11: error: no new line character at the end of file (new-line-at-end-of-file)",
+			},
+		},
+		{
+			"Default preset levels and disabled rules",
+			"extends: default\nrules:\n  trailing-spaces: disable\n",
+			"a: 1  \nb: 2 #comment\n",
+			[]string{
+				"test.yml:1:1: warning: missing document start \"---\" (document-start)",
+				"test.yml:2:6: warning: too few spaces before comment (comments)",
+				"test.yml:2:7: warning: missing starting space in comment (comments)",
+			},
+		},
+		{
+			"Brackets, colons, commas and hyphens",
+			"extends: default\nrules:\n  document-start: disable\n",
+			`a: [ 1,2 ,3]
+b:  c
+d : [ ]
+e: { f: 1 }
+url: http://example.com
+? h
+: i
+`,
+			[]string{
+				"test.yml:1:5: error: too many spaces inside brackets (brackets)",
+				"test.yml:1:8: error: too few spaces after comma (commas)",
+				"test.yml:1:9: error: too many spaces before comma (commas)",
+				"test.yml:1:11: error: too few spaces after comma (commas)",
+				"test.yml:2:4: error: too many spaces after colon (colons)",
+				"test.yml:3:2: error: too many spaces before colon (colons)",
+				"test.yml:3:6: error: too many spaces inside empty brackets (brackets)",
+				"test.yml:4:5: error: too many spaces inside braces (braces)",
+				"test.yml:4:10: error: too many spaces inside braces (braces)",
+			},
+		},
+		{
+			"Hyphens and flow sequences on multiple lines",
+			"extends: default\nrules:\n  document-start: disable\n",
+			"-   a\n- [\n    b,\n    c\n  ]\n- - d\n",
+			[]string{"test.yml:1:4: error: too many spaces after hyphen (hyphens)"},
+		},
+		{
+			"Truthy values, document start and comments indentation",
+			"extends: default\n",
+			`# Comment
+a: yes
+b: "no"
+c: !!bool on
+d:
+    # Indented comment
+  - true
+ # Not indented
+e: 1
+`,
+			[]string{
+				"test.yml:2:1: warning: missing document start \"---\" (document-start)",
+				"test.yml:2:4: warning: truthy value should be one of [false, true] (truthy)",
+				"test.yml:6:5: warning: comment not indented like content (comments-indentation)",
+				"test.yml:8:2: warning: comment not indented like content (comments-indentation)",
+			},
+		},
+		{
+			"Line length, new lines and anchors",
+			"extends: default\nrules:\n  line-length: {max: 20, allow-non-breakable-inline-mappings: true}\n  anchors: {forbid-unused-anchors: true}\n",
+			"---\r\na: &a 1\nb: *a\nc: &c this line is too long\nd: https://example.com/a/long/url\n---\ne: *c\n",
+			[]string{
+				"test.yml:1:4: error: wrong new line character: expected \\n (new-lines)",
+				"test.yml:4:4: error: found unused anchor \"c\" (anchors)",
+				"test.yml:4:21: error: line too long (27 > 20 characters) (line-length)",
+				"test.yml:7:4: error: found undeclared alias \"c\" (anchors)",
+			},
+		},
+		{
+			"Syntax error",
+			yamllintConfig,
+			"a: [\n",
+			[]string{"test.yml:1:1: error: syntax error: yaml: line 1: did not find expected node content (syntax)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linter, err := parseYAMLLintConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, issue := range linter.lint("test.yml", tt.content) {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lint() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_yamlLinter_isIgnored(t *testing.T) {
+	linter, err := parseYAMLLintConfig([]byte(yamllintConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"vendor", true, true},
+		{"e2e/_tmp", true, true},
+		{"_tmp", false, false},
+		{"e2e/.bitrise.secrets.yml", false, true},
+		{"e2e/bitrise.yml", false, false},
+	}
+	for _, tt := range tests {
+		if got := linter.isIgnored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("isIgnored(%s) got = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func Test_parseYAMLLintConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          string
+		wantUnsupported map[string]int
+		wantErr         bool
+	}{
+		{"Embedded config", yamllintConfig, map[string]int{}, false},
+		{"Unsupported rules", "rules:\n  key-ordering: enable\n  float-values: disable\n  empty-values:\n    level: warning\n", map[string]int{"key-ordering": 2, "empty-values": 4}, false},
+		{"Unknown option", "rules:\n  braces:\n    unknown: 1\n", nil, true},
+		{"Invalid level", "rules:\n  braces:\n    level: fatal\n", nil, true},
+		{"Unsupported preset", "extends: relaxed\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linter, err := parseYAMLLintConfig([]byte(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseYAMLLintConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(linter.unsupportedRules, tt.wantUnsupported) {
+				t.Errorf("parseYAMLLintConfig() unsupported rules = %v, want %v", linter.unsupportedRules, tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func Test_lintYAMLFiles_unsupportedRule(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, ".yamllint.yml"), []byte("extends: default\nrules:\n  document-start: disable\n  key-ordering: enable\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := lintYAMLFiles(dir, yamllintConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{".yamllint.yml:4:1: warning: rule key-ordering is not supported, its problems are not reported (unsupported-rule)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lintYAMLFiles() got = %#v, want %#v", got, want)
+	}
+}